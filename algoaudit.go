@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Largest KEXINIT packet accepted; real ones are a few kilobytes
+const maxKexInitPacket = 35000
+
+// SSH_MSG_KEXINIT, RFC 4253 7.1
+const msgKexInit = 20
+
+// Algorithms are the algorithm lists a server advertises in its KEXINIT,
+// recorded by -audit-algos. Ciphers and MACs are those offered for the
+// client to server direction, which servers configure alike in practice.
+type Algorithms struct {
+	Kex     []string `json:"kex"`
+	HostKey []string `json:"hostkey"`
+	Ciphers []string `json:"ciphers"`
+	MACs    []string `json:"macs"`
+}
+
+// weakAlgorithms are broken or deprecated by RFC 9142, RFC 8758 and the
+// OpenSSH release notes: SHA-1 and 1024-bit key exchange, DSA host keys, CBC
+// mode and RC4 ciphers, and MD5 or truncated MACs.
+var weakAlgorithms = []string{
+	"diffie-hellman-group1-sha1",
+	"diffie-hellman-group14-sha1",
+	"diffie-hellman-group-exchange-sha1",
+	"ssh-dss",
+	"3des-cbc",
+	"aes128-cbc",
+	"aes192-cbc",
+	"aes256-cbc",
+	"blowfish-cbc",
+	"cast128-cbc",
+	"arcfour",
+	"arcfour128",
+	"arcfour256",
+	"hmac-md5",
+	"hmac-md5-96",
+	"hmac-sha1-96",
+}
+
+// weak returns the offered algorithms found in weakAlgorithms.
+func (a *Algorithms) weak() []string {
+	var weak []string
+	for _, list := range [][]string{a.Kex, a.HostKey, a.Ciphers, a.MACs} {
+		for _, name := range list {
+			if slices.Contains(weakAlgorithms, name) && !slices.Contains(weak, name) {
+				weak = append(weak, name)
+			}
+		}
+	}
+	return weak
+}
+
+// readAlgorithms connects to addr, presents the client identification and
+// reads the server's KEXINIT, which is sent in the clear before any key is
+// agreed. The connection is closed right after, without negotiating.
+func (s *Scanner) readAlgorithms(addr string, timeout time.Duration) (*Algorithms, error) {
+	conn, err := s.dial(addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := io.WriteString(conn, cmp.Or(s.cfg.ClientVersion, defaultClientVersion)+"\r\n"); err != nil {
+		return nil, err
+	}
+	br := bufio.NewReader(conn)
+	if _, err := readBanner(br); err != nil {
+		return nil, err
+	}
+	return readKexInit(br)
+}
+
+// readKexInit reads one unencrypted binary packet (RFC 4253 6) and parses
+// it as a KEXINIT.
+func readKexInit(r io.Reader) (*Algorithms, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint32(header[:4])
+	padding := uint32(header[4])
+	if length > maxKexInitPacket || padding+1 > length {
+		return nil, fmt.Errorf("bad packet length %d", length)
+	}
+	packet := make([]byte, length-1)
+	if _, err := io.ReadFull(r, packet); err != nil {
+		return nil, err
+	}
+	payload := packet[:len(packet)-int(padding)]
+	if len(payload) < 17 || payload[0] != msgKexInit {
+		return nil, errors.New("first packet is not a KEXINIT")
+	}
+
+	// The message type and a 16-byte cookie come before the name-lists
+	rest := payload[17:]
+	lists := make([][]string, 6)
+	for i := range lists {
+		if len(rest) < 4 {
+			return nil, errors.New("truncated KEXINIT")
+		}
+		n := binary.BigEndian.Uint32(rest)
+		if uint32(len(rest)-4) < n {
+			return nil, errors.New("truncated KEXINIT")
+		}
+		if n > 0 {
+			lists[i] = strings.Split(string(rest[4:4+n]), ",")
+		}
+		rest = rest[4+n:]
+	}
+	// kex, host key, then ciphers and MACs for each direction
+	return &Algorithms{Kex: lists[0], HostKey: lists[1], Ciphers: lists[2], MACs: lists[4]}, nil
+}
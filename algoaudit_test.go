@@ -0,0 +1,45 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestScannerAuditAlgos(t *testing.T) {
+	addr, _ := startSSHServer(t, &ssh.ServerConfig{
+		Config: ssh.Config{
+			KeyExchanges: []string{"curve25519-sha256"},
+			Ciphers:      []string{"aes128-ctr", "aes128-cbc"},
+			MACs:         []string{"hmac-sha2-256", "hmac-sha1-96"},
+		},
+		PasswordCallback: func(conn ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+			return nil, errors.New("access denied")
+		},
+	})
+	host, portStr, _ := net.SplitHostPort(addr)
+	port, _ := strconv.Atoi(portStr)
+
+	cfg := &Config{Workers: 1, Port: port, User: "root", Password: "toor", Timeout: time.Second, AuditAlgos: true}
+	got := runScanner(NewScanner(cfg), host)
+
+	if len(got) != 1 || got[0].Algorithms == nil {
+		t.Fatalf("Run() = %+v, want the algorithms recorded", got)
+	}
+	algos := got[0].Algorithms
+	if !reflect.DeepEqual(algos.Ciphers, []string{"aes128-ctr", "aes128-cbc"}) || !slices.Contains(algos.Kex, "curve25519-sha256") || len(algos.HostKey) == 0 {
+		t.Errorf("Algorithms = %+v, want the server's lists", algos)
+	}
+	if want := []string{"aes128-cbc", "hmac-sha1-96"}; !reflect.DeepEqual(got[0].WeakAlgorithms, want) {
+		t.Errorf("WeakAlgorithms = %v, want %v", got[0].WeakAlgorithms, want)
+	}
+	if got[0].Category != failAuth {
+		t.Errorf("Category = %v, want the SSH attempt to go ahead and fail auth", got[0].Category)
+	}
+}
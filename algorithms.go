@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// hostKeyAlgorithms lists every host key algorithm crypto/ssh can negotiate,
+// including the insecure ones still found on legacy devices.
+func hostKeyAlgorithms() []string {
+	return append(ssh.SupportedAlgorithms().HostKeys, ssh.InsecureAlgorithms().HostKeys...)
+}
+
+// useLegacyAlgorithms widens config to every key exchange, cipher, MAC and
+// host key algorithm crypto/ssh can negotiate, insecure ones included, for
+// -legacy-fallback.
+func useLegacyAlgorithms(config *ssh.ClientConfig) {
+	supported, insecure := ssh.SupportedAlgorithms(), ssh.InsecureAlgorithms()
+	config.KeyExchanges = append(supported.KeyExchanges, insecure.KeyExchanges...)
+	config.Ciphers = append(supported.Ciphers, insecure.Ciphers...)
+	config.MACs = append(supported.MACs, insecure.MACs...)
+	config.HostKeyAlgorithms = hostKeyAlgorithms()
+}
+
+// isNoCommonAlgorithm reports whether err is a handshake that failed because
+// the server offered nothing in common with the client. Some paths only keep
+// the error text, so that is matched too.
+func isNoCommonAlgorithm(err error) bool {
+	var negotiation *ssh.AlgorithmNegotiationError
+	return errors.As(err, &negotiation) || (err != nil && strings.Contains(err.Error(), "no common algorithm"))
+}
+
+// parseAlgorithms splits a comma-separated algorithm list, keeping the given
+// order as the preference order, and rejects names not present in known.
+func parseAlgorithms(list string, known []string) ([]string, error) {
+	var algos []string
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !slices.Contains(known, name) {
+			return nil, fmt.Errorf("unsupported algorithm %q (known: %s)", name, strings.Join(known, ", "))
+		}
+		algos = append(algos, name)
+	}
+	return algos, nil
+}
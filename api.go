@@ -0,0 +1,354 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Maximum number of submitted scans waiting behind the running one
+const apiQueueSize = 16
+
+// Finished scans stay queryable for apiJobTTL, and only the last
+// apiFinishedJobs of them, so a long-running server doesn't grow with every
+// submission.
+const (
+	apiJobTTL       = time.Hour
+	apiFinishedJobs = 256
+)
+
+// Largest POST /scans body accepted
+const apiMaxBody = 64 << 10
+
+// scanRequest is the body accepted by POST /scans. Empty fields fall back to
+// the defaults the server was started with.
+type scanRequest struct {
+	Target   string `json:"target"`
+	User     string `json:"user"`
+	Password string `json:"password"`
+	Port     int    `json:"port"`
+	Workers  int    `json:"workers"`
+	Timeout  string `json:"timeout"`
+	Tag      string `json:"tag"`
+}
+
+type apiJob struct {
+	id      string
+	cfg     *Config
+	specs   []string
+	total   uint64
+	scanner *Scanner
+
+	mu         sync.Mutex
+	status     string
+	found      []Result
+	startedAt  time.Time
+	finishedAt time.Time
+}
+
+type jobStatus struct {
+	ID         string     `json:"id"`
+	ScanID     string     `json:"scan_id"`
+	Tag        string     `json:"tag,omitempty"`
+	Target     string     `json:"target"`
+	Status     string     `json:"status"`
+	Total      uint64     `json:"total"`
+	Stats      Stats      `json:"stats"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+func (j *apiJob) snapshot() jobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	st := jobStatus{
+		ID:     j.id,
+		ScanID: j.cfg.ScanID,
+		Tag:    j.cfg.Tag,
+		Target: j.cfg.CIDR,
+		Status: j.status,
+		Total:  j.total,
+		Stats:  j.scanner.Stats(),
+	}
+	if !j.startedAt.IsZero() {
+		st.StartedAt = &j.startedAt
+	}
+	if !j.finishedAt.IsZero() {
+		st.FinishedAt = &j.finishedAt
+	}
+	return st
+}
+
+type apiServer struct {
+	defaults *Config
+
+	mu    sync.Mutex
+	jobs  map[string]*apiJob
+	queue chan *apiJob
+}
+
+// serveAPI runs the HTTP API until the listener fails. Scans are started in
+// submission order, -serve-jobs at a time; running side by side, they share a
+// Limiter so their connections together stay within -w.
+func serveAPI(cfg *Config) error {
+	if cfg.APIToken == "" {
+		return errors.New("an API token is required (-api-token or SSH_SCANNER_API_TOKEN)")
+	}
+	if cfg.ServeJobs > 1 {
+		cfg.Limiter = NewLimiter(cfg.Workers)
+	}
+
+	srv := &apiServer{
+		defaults: cfg,
+		jobs:     make(map[string]*apiJob),
+		queue:    make(chan *apiJob, apiQueueSize),
+	}
+	for range max(cfg.ServeJobs, 1) {
+		go srv.runQueue()
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /scans", srv.handleSubmit)
+	mux.HandleFunc("GET /scans/{id}", srv.handleStatus)
+	mux.HandleFunc("GET /scans/{id}/results", srv.handleResults)
+
+	fmt.Printf("%sAPI listening on %s%s\n", ColorCyan, cfg.Serve, ColorReset)
+	return http.ListenAndServe(cfg.Serve, requireToken(cfg.APIToken, mux))
+}
+
+func requireToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			writeError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (srv *apiServer) runQueue() {
+	for job := range srv.queue {
+		srv.runJob(job)
+	}
+}
+
+func (srv *apiServer) runJob(job *apiJob) {
+	job.mu.Lock()
+	job.status = "running"
+	job.startedAt = time.Now()
+	job.mu.Unlock()
+
+	// Targets were validated on submit
+	targets, _, err := rangeTargets(job.specs, job.cfg.Ports, job.cfg.bufferSize())
+	if err != nil {
+		targets = sliceTargets(nil)
+	}
+
+	results := make(chan Result, job.cfg.Workers)
+	go job.scanner.Run(context.Background(), targets, results)
+	var reported <-chan Result = results
+	if job.cfg.ResolveNames {
+		reported = resolveNames(results, net.DefaultResolver.LookupAddr, job.cfg)
+	}
+
+	for res := range reported {
+		if res.Success {
+			job.mu.Lock()
+			job.found = append(job.found, res)
+			job.mu.Unlock()
+		}
+	}
+
+	job.mu.Lock()
+	job.status = "done"
+	job.finishedAt = time.Now()
+	job.mu.Unlock()
+}
+
+func (srv *apiServer) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	var req scanRequest
+	r.Body = http.MaxBytesReader(w, r.Body, apiMaxBody)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return
+	}
+
+	cfg, err := srv.jobConfig(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	// The same comma lists and shortcuts as the command line
+	specs, err := splitTargets(cfg.CIDR)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid target: "+err.Error())
+		return
+	}
+	var total uint64
+	for _, spec := range expandShortcuts(specs) {
+		_, ipNet, _ := parseInput(spec)
+		total += countIPs(ipNet) * uint64(max(len(cfg.Ports), 1))
+	}
+
+	job := &apiJob{
+		id:      newJobID(),
+		cfg:     cfg,
+		specs:   specs,
+		total:   total,
+		scanner: NewScanner(cfg),
+		status:  "queued",
+	}
+
+	select {
+	case srv.queue <- job:
+	default:
+		writeError(w, http.StatusServiceUnavailable, "scan queue is full")
+		return
+	}
+
+	srv.mu.Lock()
+	srv.evict(time.Now())
+	srv.jobs[job.id] = job
+	srv.mu.Unlock()
+
+	writeJSON(w, http.StatusAccepted, job.snapshot())
+}
+
+// jobConfig merges a request with the server defaults and validates the result.
+func (srv *apiServer) jobConfig(req scanRequest) (*Config, error) {
+	cfg := *srv.defaults
+	cfg.CIDR = req.Target
+	cfg.ScanID = newScanID()
+	if req.Tag != "" {
+		cfg.Tag = req.Tag
+	}
+	if req.User != "" {
+		cfg.User = req.User
+	}
+	if req.Password != "" {
+		cfg.Password = req.Password
+	}
+	if req.Port != 0 {
+		// A port of the job's own replaces the server's -ports
+		cfg.Port = req.Port
+		cfg.Ports = nil
+	}
+	if req.Workers != 0 {
+		// Up to the server's -w, which callers can't raise
+		cfg.Workers = min(req.Workers, srv.defaults.Workers)
+	}
+	if req.Timeout != "" {
+		timeout, err := time.ParseDuration(req.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout: %v", err)
+		}
+		if timeout <= 0 {
+			// Zero would leave dials and handshakes without a deadline
+			return nil, fmt.Errorf("timeout %v must be positive", timeout)
+		}
+		cfg.Timeout = timeout
+	}
+
+	switch {
+	case cfg.CIDR == "":
+		return nil, errors.New("target is required")
+	case cfg.Port < 1 || cfg.Port > 65535:
+		return nil, fmt.Errorf("port %d out of range", cfg.Port)
+	case cfg.Workers < 1:
+		return nil, errors.New("workers must be positive")
+	}
+	return &cfg, nil
+}
+
+// evict forgets finished scans older than apiJobTTL at now, and the oldest
+// ones beyond apiFinishedJobs. Queued and running scans are kept. The caller
+// holds srv.mu.
+func (srv *apiServer) evict(now time.Time) {
+	type finished struct {
+		id string
+		at time.Time
+	}
+	var done []finished
+	for id, job := range srv.jobs {
+		job.mu.Lock()
+		at := job.finishedAt
+		job.mu.Unlock()
+		switch {
+		case at.IsZero():
+		case now.Sub(at) > apiJobTTL:
+			delete(srv.jobs, id)
+		default:
+			done = append(done, finished{id, at})
+		}
+	}
+	if len(done) <= apiFinishedJobs {
+		return
+	}
+	slices.SortFunc(done, func(a, b finished) int { return a.at.Compare(b.at) })
+	for _, f := range done[:len(done)-apiFinishedJobs] {
+		delete(srv.jobs, f.id)
+	}
+}
+
+func (srv *apiServer) lookup(w http.ResponseWriter, r *http.Request) *apiJob {
+	srv.mu.Lock()
+	job := srv.jobs[r.PathValue("id")]
+	srv.mu.Unlock()
+
+	if job == nil {
+		writeError(w, http.StatusNotFound, "unknown scan id")
+	}
+	return job
+}
+
+func (srv *apiServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if job := srv.lookup(w, r); job != nil {
+		writeJSON(w, http.StatusOK, job.snapshot())
+	}
+}
+
+func (srv *apiServer) handleResults(w http.ResponseWriter, r *http.Request) {
+	job := srv.lookup(w, r)
+	if job == nil {
+		return
+	}
+
+	job.mu.Lock()
+	found := append([]Result{}, job.found...)
+	status := job.status
+	job.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"id":      job.id,
+		"status":  status,
+		"results": found,
+	})
+}
+
+func newJobID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]string{"error": msg})
+}
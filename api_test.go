@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJobConfig(t *testing.T) {
+	srv := &apiServer{defaults: &Config{User: "test", Password: "123456", Port: 22, Workers: 100, Timeout: 3 * time.Second}}
+
+	cfg, err := srv.jobConfig(scanRequest{Target: "10.0.0.0/24", User: "root", Timeout: "1s", Tag: "q3-audit"})
+	if err != nil {
+		t.Fatalf("jobConfig() error = %v", err)
+	}
+	if cfg.CIDR != "10.0.0.0/24" || cfg.User != "root" || cfg.Password != "123456" || cfg.Timeout != time.Second {
+		t.Errorf("jobConfig() = %+v, want request values merged over defaults", cfg)
+	}
+	if cfg.Tag != "q3-audit" || cfg.ScanID == "" {
+		t.Errorf("jobConfig() tag %q, scan ID %q; want the request tag and a fresh ID", cfg.Tag, cfg.ScanID)
+	}
+	if cfg, _ := srv.jobConfig(scanRequest{Target: "10.0.0.1", Workers: 5000}); cfg.Workers != 100 {
+		t.Errorf("jobConfig() workers = %d, want the server's -w of 100", cfg.Workers)
+	}
+	if srv.defaults.User != "test" {
+		t.Errorf("jobConfig() modified server defaults")
+	}
+
+	invalid := []scanRequest{
+		{},
+		{Target: "10.0.0.1", Port: 70000},
+		{Target: "10.0.0.1", Workers: -1},
+		{Target: "10.0.0.1", Timeout: "soon"},
+		{Target: "10.0.0.1", Timeout: "0s"},
+		{Target: "10.0.0.1", Timeout: "-1s"},
+	}
+	for _, req := range invalid {
+		if _, err := srv.jobConfig(req); err == nil {
+			t.Errorf("jobConfig(%+v) expected error", req)
+		}
+	}
+}
+
+func TestAPIEvict(t *testing.T) {
+	now := time.Now()
+	srv := &apiServer{jobs: make(map[string]*apiJob)}
+	add := func(id string, finishedAt time.Time) {
+		srv.jobs[id] = &apiJob{id: id, finishedAt: finishedAt}
+	}
+	add("running", time.Time{})
+	add("expired", now.Add(-apiJobTTL-time.Minute))
+	for i := range apiFinishedJobs + 2 {
+		add(fmt.Sprintf("done-%03d", i), now.Add(time.Duration(i-apiFinishedJobs-2)*time.Second))
+	}
+
+	srv.evict(now)
+	for _, id := range []string{"expired", "done-000", "done-001"} {
+		if srv.jobs[id] != nil {
+			t.Errorf("evict() kept %s", id)
+		}
+	}
+	if srv.jobs["running"] == nil || srv.jobs["done-002"] == nil {
+		t.Error("evict() dropped a running scan or one of the newest finished ones")
+	}
+	if want := apiFinishedJobs + 1; len(srv.jobs) != want {
+		t.Errorf("evict() left %d jobs, want %d", len(srv.jobs), want)
+	}
+}
+
+func TestAPISubmit(t *testing.T) {
+	srv := &apiServer{
+		defaults: &Config{User: "test", Port: 22, Ports: []int{22, 2222}, Workers: 10, Timeout: time.Second},
+		jobs:     make(map[string]*apiJob),
+		queue:    make(chan *apiJob, 1),
+	}
+	submit := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		srv.handleSubmit(w, httptest.NewRequest("POST", "/scans", strings.NewReader(body)))
+		return w
+	}
+
+	// Comma lists and the server's -ports, as on the command line
+	if w := submit(`{"target":"10.0.0.0/30,10.0.1.5"}`); w.Code != http.StatusAccepted {
+		t.Fatalf("submit: %d %s", w.Code, w.Body)
+	}
+	job := <-srv.queue
+	if job.total != 10 {
+		t.Errorf("job total = %d, want 5 addresses on 2 ports", job.total)
+	}
+
+	big := `{"target":"10.0.0.1","tag":"` + strings.Repeat("x", apiMaxBody) + `"}`
+	if w := submit(big); w.Code != http.StatusBadRequest {
+		t.Errorf("oversized body: %d, want 400", w.Code)
+	}
+}
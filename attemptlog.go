@@ -0,0 +1,70 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Random bytes in an attempt ID; it takes twice as many hex digits, plus a
+// space, in the identification string
+const attemptIDBytes = 8
+
+// attemptLog writes the -attempt-log CSV: one row per attempt with the ID it
+// sent to the server, so tester and defender can match the server's logs to
+// the scan. Rows are flushed as they are written, since the log is most useful
+// while the test is still running.
+type attemptLog struct {
+	mu sync.Mutex
+	f  *os.File
+	w  *csv.Writer
+}
+
+func createAttemptLog(path string) (*attemptLog, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	l := &attemptLog{f: f, w: csv.NewWriter(f)}
+	l.w.Write([]string{"time", "attempt_id", "ip", "port", "user"})
+	l.w.Flush()
+	return l, nil
+}
+
+func (l *attemptLog) add(now time.Time, id string, t Target) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.w.Write([]string{now.UTC().Format(time.RFC3339Nano), id, t.Host, strconv.Itoa(t.Port), t.User})
+	l.w.Flush()
+}
+
+// Close reports the first write error, if any.
+func (l *attemptLog) Close() error {
+	return errors.Join(l.w.Error(), l.f.Close())
+}
+
+func newAttemptID() string {
+	b := make([]byte, attemptIDBytes)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// tagAttempt gives the attempt on t an ID when -attempt-log is set. The ID
+// goes into the comment part of the SSH identification string, which sshd
+// logs with the connection, and into res and the log.
+func (s *Scanner) tagAttempt(t Target, config *ssh.ClientConfig, res *Result) {
+	if s.attempts == nil {
+		return
+	}
+	id := newAttemptID()
+	config.ClientVersion += " " + id
+	res.AttemptID = id
+	s.attempts.add(time.Now(), id, t)
+}
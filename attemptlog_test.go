@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/csv"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestScannerAttemptLog(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		versions []string
+	)
+	addr, _ := startSSHServer(t, &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+			mu.Lock()
+			versions = append(versions, string(conn.ClientVersion()))
+			mu.Unlock()
+			return &ssh.Permissions{}, nil
+		},
+	})
+	host, portStr, _ := net.SplitHostPort(addr)
+	port, _ := strconv.Atoi(portStr)
+
+	path := filepath.Join(t.TempDir(), "attempts.csv")
+	log, err := createAttemptLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewScanner(&Config{Workers: 1, Port: port, User: "root", Password: "toor", Timeout: time.Second})
+	s.attempts = log
+	got := runScanner(s, host)
+	if err := log.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != 1 || !got[0].Success || len(got[0].AttemptID) != 2*attemptIDBytes {
+		t.Fatalf("Run() = %+v, want a success with an attempt ID", got)
+	}
+	id := got[0].AttemptID
+	if want := defaultClientVersion + " " + id; len(versions) != 1 || versions[0] != want {
+		t.Errorf("server saw client versions %q, want %q", versions, want)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[1][1] != id || rows[1][2] != host || rows[1][3] != portStr || rows[1][4] != "root" {
+		t.Errorf("attempt log = %q, want a header and one row for %s", rows, id)
+	}
+}
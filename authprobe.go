@@ -0,0 +1,38 @@
+package main
+
+import (
+	"errors"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// errProbeOnly stops each auth method before any credential is sent.
+var errProbeOnly = errors.New("auth method probe")
+
+// probeAuth returns auth methods that record which ones the server offers
+// without ever sending a credential. The client only tries a method after
+// the server listed it in response to the initial "none" request, so each
+// callback firing means the method is accepted by the server.
+//
+// Only the methods crypto/ssh implements can be seen: publickey, password
+// and keyboard-interactive. The latter is recorded when the server sends
+// its first prompt, which is answered with an error instead.
+func probeAuth(methods *[]string) []ssh.AuthMethod {
+	record := func(method string) {
+		*methods = append(*methods, method)
+	}
+	return []ssh.AuthMethod{
+		ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+			record("publickey")
+			return nil, errProbeOnly
+		}),
+		ssh.PasswordCallback(func() (string, error) {
+			record("password")
+			return "", errProbeOnly
+		}),
+		ssh.KeyboardInteractive(func(name, instruction string, questions []string, echos []bool) ([]string, error) {
+			record("keyboard-interactive")
+			return nil, errProbeOnly
+		}),
+	}
+}
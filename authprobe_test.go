@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestScannerProbeAuthMethods(t *testing.T) {
+	var passwords atomic.Int32
+	addr, _ := startSSHServer(t, &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+			passwords.Add(1)
+			return nil, errors.New("access denied")
+		},
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			return nil, errors.New("access denied")
+		},
+	})
+	open, _ := startSSHServer(t, &ssh.ServerConfig{NoClientAuth: true})
+
+	for _, tt := range []struct {
+		addr string
+		want []string
+	}{
+		{addr, []string{"publickey", "password"}},
+		{open, []string{"none"}},
+	} {
+		host, portStr, _ := net.SplitHostPort(tt.addr)
+		port, _ := strconv.Atoi(portStr)
+
+		cfg := &Config{Workers: 1, Port: port, User: "root", Password: "toor", Timeout: time.Second, ProbeAuth: true}
+		got := runScanner(NewScanner(cfg), host)
+
+		if len(got) != 1 || !got[0].Success || !reflect.DeepEqual(got[0].AuthMethods, tt.want) {
+			t.Errorf("Run() against %s = %+v, want auth methods %v", tt.addr, got, tt.want)
+		}
+	}
+	if n := passwords.Load(); n != 0 {
+		t.Errorf("server received %d password attempts during a probe", n)
+	}
+}
+
+func TestScannerNoneAuth(t *testing.T) {
+	var passwords atomic.Int32
+	locked, _ := startSSHServer(t, &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+			passwords.Add(1)
+			return &ssh.Permissions{}, nil
+		},
+	})
+	open, _ := startSSHServer(t, &ssh.ServerConfig{NoClientAuth: true})
+
+	for _, tt := range []struct {
+		addr  string
+		found bool
+	}{
+		{locked, false},
+		{open, true},
+	} {
+		host, portStr, _ := net.SplitHostPort(tt.addr)
+		port, _ := strconv.Atoi(portStr)
+
+		cfg := &Config{Workers: 1, Port: port, User: "root", Password: "toor", Timeout: time.Second, NoneAuth: true}
+		got := runScanner(NewScanner(cfg), host)
+
+		if len(got) != 1 || got[0].Success != tt.found {
+			t.Fatalf("Run() against %s = %+v, want success=%v", tt.addr, got, tt.found)
+		}
+		if !tt.found && got[0].Category != failAuth {
+			t.Errorf("Run() against %s: Category = %v, want auth", tt.addr, got[0].Category)
+		}
+	}
+	if n := passwords.Load(); n != 0 {
+		t.Errorf("server received %d password attempts with -none-auth", n)
+	}
+}
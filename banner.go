@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// Lines a server may send before its identification string (RFC 4253 4.2)
+const maxBannerLines = 16
+
+// errNotSSH marks open ports whose service did not identify as SSH.
+var errNotSSH = errors.New("not an SSH server")
+
+// errBannerMismatch marks SSH servers whose identification string does not
+// match -banner-match.
+var errBannerMismatch = errors.New("banner does not match -banner-match")
+
+// checkBanner connects to addr and waits for the SSH identification string,
+// so -detect-ssh only spends credential attempts on real SSH services. It
+// returns the identification string.
+func (s *Scanner) checkBanner(addr string, timeout time.Duration) (string, error) {
+	conn, err := s.dial(addr, timeout)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(timeout))
+	return readBanner(conn)
+}
+
+// readBanner reads lines until one starts with "SSH-" and returns that line
+// without its line ending. Services that say something else, or stay silent
+// waiting for the client as HTTP does, are reported as errNotSSH.
+func readBanner(r io.Reader) (string, error) {
+	// Reuses r when it is a large enough bufio.Reader, so the caller can go
+	// on reading after the banner
+	br := bufio.NewReaderSize(r, 256)
+	var first string
+	for range maxBannerLines {
+		line, err := br.ReadSlice('\n')
+		if strings.HasPrefix(string(line), "SSH-") {
+			return strings.TrimRight(string(line), "\r\n"), nil
+		}
+		if first == "" {
+			first = string(line)
+		}
+
+		switch {
+		case err == nil:
+		case first != "":
+			return "", fmt.Errorf("%w: got %q", errNotSSH, truncate(first, 40))
+		case isTimeout(err):
+			return "", fmt.Errorf("%w: nothing received before the timeout", errNotSSH)
+		default:
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("%w: no identification string in the first %d lines", errNotSSH, maxBannerLines)
+}
+
+// bannerConn bounds the wait for the server's identification string during
+// the SSH handshake: reads fail once the deadline set by newBannerConn passes,
+// until a line starting with "SSH-" has been received.
+type bannerConn struct {
+	net.Conn
+	timeout time.Duration
+	line    []byte // start of the line being received, enough to spot "SSH-"
+	waiting bool
+}
+
+func newBannerConn(conn net.Conn, timeout time.Duration) *bannerConn {
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	return &bannerConn{Conn: conn, timeout: timeout, waiting: true}
+}
+
+func (c *bannerConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if !c.waiting {
+		return n, err
+	}
+	for _, b := range p[:n] {
+		if b != '\n' {
+			if len(c.line) < len("SSH-") {
+				c.line = append(c.line, b)
+			}
+			continue
+		}
+		if string(c.line) == "SSH-" {
+			c.waiting = false
+			c.Conn.SetReadDeadline(time.Time{})
+			break
+		}
+		c.line = c.line[:0]
+	}
+	if c.waiting && isTimeout(err) {
+		err = fmt.Errorf("no SSH banner within %v: %w", c.timeout, err)
+	}
+	return n, err
+}
+
+func truncate(s string, n int) string {
+	s = strings.TrimRight(s, "\r\n")
+	if len(s) > n {
+		return s[:n] + "..."
+	}
+	return s
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestReadBanner(t *testing.T) {
+	tests := []struct {
+		greeting string
+		notSSH   bool
+	}{
+		{"SSH-2.0-OpenSSH_9.6\r\n", false},
+		{"Authorized use only\r\nSSH-2.0-dropbear\r\n", false},
+		{"HTTP/1.1 400 Bad Request\r\n\r\n", true},
+		{"", true}, // silent until the client speaks, like HTTP
+	}
+	for _, tt := range tests {
+		client, server := net.Pipe()
+		go func() {
+			server.Write([]byte(tt.greeting))
+		}()
+		client.SetDeadline(time.Now().Add(100 * time.Millisecond))
+
+		_, err := readBanner(client)
+		if got := errors.Is(err, errNotSSH); got != tt.notSSH || (!tt.notSSH && err != nil) {
+			t.Errorf("readBanner(%q) = %v, want notSSH=%v", tt.greeting, err, tt.notSSH)
+		}
+		client.Close()
+		server.Close()
+	}
+}
+
+func TestScannerDetectSSHSkipsOtherServices(t *testing.T) {
+	addr, _ := startTestServer(t, "toor")
+	host, portStr, _ := net.SplitHostPort(addr)
+	sshPort, _ := strconv.Atoi(portStr)
+
+	// A web server that answers with its own protocol
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Write([]byte("HTTP/1.1 400 Bad Request\r\n\r\n"))
+			conn.Close()
+		}
+	}()
+	httpPort := ln.Addr().(*net.TCPAddr).Port
+
+	cfg := &Config{Workers: 2, User: "root", Password: "toor", Timeout: time.Second,
+		Ports: []int{sshPort, httpPort}, DetectSSH: true, Precheck: 500 * time.Millisecond}
+	s := NewScanner(cfg)
+
+	var (
+		mu    sync.Mutex
+		tried []string
+	)
+	connect := s.connect
+	s.connect = func(addr string, config *ssh.ClientConfig) error {
+		mu.Lock()
+		tried = append(tried, addr)
+		mu.Unlock()
+		return connect(addr, config)
+	}
+
+	targets, _, err := rangeTargets([]string{host}, cfg.Ports, cfg.bufferSize())
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := make(chan Result)
+	go s.Run(t.Context(), targets, results)
+	for range results {
+	}
+
+	if len(tried) != 1 || tried[0] != addr {
+		t.Errorf("credentials tried on %v, want only %s", tried, addr)
+	}
+	if stats := s.Stats(); stats.Found != 1 || stats.NotSSH != 1 {
+		t.Errorf("Stats() = %+v, want Found=1 NotSSH=1", stats)
+	}
+}
+
+func TestScannerBannerTimeout(t *testing.T) {
+	// A tarpit: accepts the connection, then never says anything
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close() // Held open until the test ends
+		}
+	}()
+	tarpit := ln.Addr().(*net.TCPAddr)
+	sshAddr, _ := startTestServer(t, "toor")
+	sshHost, sshPort, _ := net.SplitHostPort(sshAddr)
+	port, _ := strconv.Atoi(sshPort)
+
+	cfg := &Config{Workers: 2, User: "root", Password: "toor", Timeout: time.Minute, BannerTimeout: 200 * time.Millisecond}
+	in := make(chan Target, 2)
+	in <- Target{Host: tarpit.IP.String(), Port: tarpit.Port}
+	in <- Target{Host: sshHost, Port: port}
+	close(in)
+
+	start := time.Now()
+	out := make(chan Result)
+	go NewScanner(cfg).Run(context.Background(), in, out)
+	for res := range out {
+		switch res.Port {
+		case tarpit.Port:
+			if res.Success || res.Category != failTimeout || !strings.Contains(res.Error, "no SSH banner") {
+				t.Errorf("tarpit: %+v, want a banner timeout", res)
+			}
+		default:
+			// The handshake goes on past the banner without the deadline
+			if !res.Success {
+				t.Errorf("SSH server: %+v, want success", res)
+			}
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("scan took %v; the tarpit should be dropped after the banner timeout", elapsed)
+	}
+}
+
+func TestScannerBannerMatch(t *testing.T) {
+	var passwords atomic.Int32
+	addr, _ := startSSHServer(t, &ssh.ServerConfig{
+		ServerVersion: "SSH-2.0-dropbear_2022.83",
+		PasswordCallback: func(conn ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+			passwords.Add(1)
+			return &ssh.Permissions{}, nil
+		},
+	})
+	host, portStr, _ := net.SplitHostPort(addr)
+	port, _ := strconv.Atoi(portStr)
+
+	for _, tt := range []struct {
+		match string
+		found bool
+	}{
+		{`dropbear`, true},
+		{`OpenSSH_7\.`, false},
+	} {
+		passwords.Store(0)
+		cfg := &Config{Workers: 1, Port: port, User: "root", Password: "toor", Timeout: time.Second,
+			DetectSSH: true, BannerMatch: regexp.MustCompile(tt.match)}
+		s := NewScanner(cfg)
+		got := runScanner(s, host)
+
+		if len(got) != 1 || got[0].Success != tt.found || got[0].Banner != "SSH-2.0-dropbear_2022.83" {
+			t.Fatalf("-banner-match %s: Run() = %+v, want success=%v with the banner", tt.match, got, tt.found)
+		}
+		if tt.found {
+			continue
+		}
+		if got[0].Category != failUnmatched || s.Stats().Unmatched != 1 {
+			t.Errorf("-banner-match %s: Category = %v, Unmatched = %d; want unmatched, 1", tt.match, got[0].Category, s.Stats().Unmatched)
+		}
+		if n := passwords.Load(); n != 0 {
+			t.Errorf("-banner-match %s: server received %d password attempts", tt.match, n)
+		}
+	}
+}
+
+func TestScannerAuthTimeout(t *testing.T) {
+	// Sends its banner, then stalls the key exchange
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close() // Held open until the test ends
+			conn.Write([]byte("SSH-2.0-stall\r\n"))
+		}
+	}()
+	stall := ln.Addr().(*net.TCPAddr)
+	sshAddr, _ := startTestServer(t, "toor")
+	sshHost, sshPort, _ := net.SplitHostPort(sshAddr)
+	port, _ := strconv.Atoi(sshPort)
+
+	// The banner arrives in time, so only -auth-timeout can end the attempt
+	cfg := &Config{Workers: 2, User: "root", Password: "toor", Timeout: time.Second,
+		BannerTimeout: time.Minute, AuthTimeout: 200 * time.Millisecond}
+	in := make(chan Target, 2)
+	in <- Target{Host: stall.IP.String(), Port: stall.Port}
+	in <- Target{Host: sshHost, Port: port}
+	close(in)
+
+	start := time.Now()
+	out := make(chan Result)
+	go NewScanner(cfg).Run(context.Background(), in, out)
+	for res := range out {
+		switch res.Port {
+		case stall.Port:
+			if res.Success || res.Category != failTimeout || !errors.Is(res.err, errAuthTimeout) {
+				t.Errorf("stalled server: %+v, want an auth timeout", res)
+			}
+		default:
+			if !res.Success {
+				t.Errorf("SSH server: %+v, want success", res)
+			}
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("scan took %v; the stalled server should be dropped after -auth-timeout", elapsed)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// A port that refuses a connection within banWindow of accepting one looks
+// like fail2ban kicking in, rather than a service that was never there
+const banWindow = time.Minute
+
+// banWatch tracks the hosts that banned the scanner. Once banned, a host is
+// not attempted again for the rest of the run. The zero value is ready to use.
+type banWatch struct {
+	mu       sync.Mutex
+	accepted map[string]time.Time // last accepted connection per host:port
+	banned   map[string]bool
+}
+
+// connected notes that addr accepted a connection.
+func (w *banWatch) connected(addr string, now time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.accepted == nil {
+		w.accepted = make(map[string]time.Time)
+	}
+	w.accepted[addr] = now
+}
+
+// refused notes that addr refused a connection and reports whether that
+// bans its host: addr accepted a connection less than banWindow before.
+func (w *banWatch) refused(addr string, now time.Time) bool {
+	w.mu.Lock()
+	last, ok := w.accepted[addr]
+	w.mu.Unlock()
+	if !ok || now.Sub(last) >= banWindow {
+		return false
+	}
+	host, _, _ := net.SplitHostPort(addr)
+	w.ban(host)
+	return true
+}
+
+func (w *banWatch) ban(host string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.banned == nil {
+		w.banned = make(map[string]bool)
+	}
+	w.banned[host] = true
+}
+
+func (w *banWatch) isBanned(host string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.banned[host]
+}
+
+// count returns the number of banned hosts.
+func (w *banWatch) count() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.banned)
+}
+
+// checkBan passes on the outcome of a direct dial to addr, turning a refusal
+// shortly after an accepted connection into a ban.
+func (s *Scanner) checkBan(addr string, conn net.Conn, err error) (net.Conn, error) {
+	now := time.Now()
+	switch {
+	case err == nil:
+		s.bans.connected(addr, now)
+	case classifyError(err) == failRefused && s.bans.refused(addr, now):
+		return nil, fmt.Errorf("%w: refused right after accepting a connection: %v", errBanned, err)
+	}
+	return conn, err
+}
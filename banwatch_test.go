@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestBanWatch(t *testing.T) {
+	var w banWatch
+	now := time.Now()
+
+	if w.refused("10.0.0.1:22", now) {
+		t.Error("a port that never accepted a connection counts as a ban")
+	}
+	w.connected("10.0.0.1:22", now)
+	if w.refused("10.0.0.1:2222", now) {
+		t.Error("a refusal on another port counts as a ban")
+	}
+	if w.refused("10.0.0.1:22", now.Add(banWindow)) {
+		t.Error("a refusal after banWindow counts as a ban")
+	}
+	if !w.refused("10.0.0.1:22", now.Add(time.Second)) {
+		t.Error("a refusal right after an accepted connection is not a ban")
+	}
+	if !w.isBanned("10.0.0.1") || w.isBanned("10.0.0.2") || w.count() != 1 {
+		t.Errorf("banned hosts = %v, want only 10.0.0.1", w.banned)
+	}
+}
+
+func TestScannerStopsOnBannedHost(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	host, portStr, _ := net.SplitHostPort(ln.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+
+	cfg := &Config{Workers: 1, Port: port, User: "root", Timeout: time.Second, Precheck: time.Second}
+	s := NewScanner(cfg)
+	calls := 0
+	s.connect = func(addr string, config *ssh.ClientConfig) error {
+		// The first attempt gets through, then the host bans us
+		calls++
+		ln.Close()
+		return errors.New("ssh: handshake failed: ssh: unable to authenticate")
+	}
+
+	in := make(chan Target, 3)
+	for _, t := range []Target{{Host: host}, {Host: host, User: "admin"}, {Host: host, Port: port + 1}} {
+		in <- t
+	}
+	close(in)
+	out := make(chan Result)
+	go s.Run(context.Background(), in, out)
+
+	var got []failureCategory
+	for res := range out {
+		got = append(got, res.Category)
+	}
+	if len(got) != 3 || got[0] != failAuth || got[1] != failBanned || got[2] != failBanned {
+		t.Errorf("categories = %v, want auth then banned twice", got)
+	}
+	if calls != 1 {
+		t.Errorf("%d SSH attempts, want only the one before the ban", calls)
+	}
+	if n := s.Stats().Banned; n != 1 {
+		t.Errorf("Stats().Banned = %d, want 1", n)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// Defaults of -breaker-window and -breaker-cooldown
+const (
+	defaultBreakerWindow   = 100
+	defaultBreakerCooldown = 5 * time.Minute
+)
+
+// circuitBreaker watches the last attempts for -breaker and trips when too
+// many of them failed in a way that points at the path to the targets
+// rather than at the targets: timeouts, unreachable networks, dropped
+// connections and bans. A scan that keeps going then only burns through its
+// target list against a dead network or a block, so the scanner pauses.
+type circuitBreaker struct {
+	threshold float64
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	recent    []bool // ring of the last attempts, true for a failure
+	next      int
+	filled    int
+	failed    int
+	trips     int
+	openUntil time.Time
+}
+
+func newCircuitBreaker(threshold float64, window int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown, recent: make([]bool, window)}
+}
+
+// pathFailure reports whether a failure of category c suggests the network
+// path or a block rather than the target itself.
+func pathFailure(c failureCategory) bool {
+	switch c {
+	case failTimeout, failUnreachable, failReset, failBanned:
+		return true
+	}
+	return false
+}
+
+// add records the outcome of one attempt at now and reports whether it
+// tripped the breaker. Only a full window can trip it, and tripping starts
+// the window over, so the attempts still in flight when the scan paused
+// don't trip it again right after the cooldown.
+func (b *circuitBreaker) add(failed bool, now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.filled == len(b.recent) {
+		if b.recent[b.next] {
+			b.failed--
+		}
+	} else {
+		b.filled++
+	}
+	b.recent[b.next] = failed
+	b.next = (b.next + 1) % len(b.recent)
+	if failed {
+		b.failed++
+	}
+
+	if b.filled < len(b.recent) || float64(b.failed) < b.threshold*float64(b.filled) {
+		return false
+	}
+	clear(b.recent)
+	b.next, b.filled, b.failed = 0, 0, 0
+	b.trips++
+	b.openUntil = now.Add(b.cooldown)
+	return true
+}
+
+// state returns how often the breaker tripped and until when the last trip
+// pauses the scan.
+func (b *circuitBreaker) state() (trips int, openUntil time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.trips, b.openUntil
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// There is no spare signal to resume a -breaker pause early here; the
+// -tui pause key does it instead
+var resumeSignals []os.Signal
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Now()
+	b := newCircuitBreaker(0.75, 4, time.Minute)
+
+	// Three failures in a window of four are enough, but not before the
+	// window is full
+	for i, failed := range []bool{true, true, true} {
+		if b.add(failed, now) {
+			t.Fatalf("add() #%d tripped with the window not yet full", i)
+		}
+	}
+	if !b.add(false, now) {
+		t.Fatal("add() did not trip at 3 failures of 4")
+	}
+	if trips, until := b.state(); trips != 1 || !until.Equal(now.Add(time.Minute)) {
+		t.Errorf("state() = %d, %v; want 1 trip open for a minute", trips, until)
+	}
+
+	// Tripping starts the window over, and successes push failures out
+	for i, failed := range []bool{true, true, false, false, true, false} {
+		if b.add(failed, now) {
+			t.Fatalf("add() #%d tripped again", i)
+		}
+	}
+}
+
+func TestScannerBreaker(t *testing.T) {
+	cfg := &Config{Workers: 1, Port: 22, Timeout: time.Second,
+		Breaker: 1, BreakerWindow: 3, BreakerCooldown: 50 * time.Millisecond}
+	s := NewScanner(cfg)
+	s.connect = func(addr string, config *ssh.ClientConfig) error {
+		return timeoutError{}
+	}
+
+	ips := make([]string, 6)
+	for i := range ips {
+		ips[i] = fmt.Sprintf("10.0.0.%d", i+1)
+	}
+	start := time.Now()
+	if got := runScanner(s, ips...); len(got) != len(ips) {
+		t.Fatalf("Run() produced %d results, want %d", len(got), len(ips))
+	}
+
+	if trips, _ := s.Breaker(); trips != 2 {
+		t.Errorf("Breaker() tripped %d times, want 2", trips)
+	}
+	// The second trip came with the last attempt, leaving nothing to hold
+	if elapsed := time.Since(start); elapsed < cfg.BreakerCooldown {
+		t.Errorf("scan took %v, want at least one -breaker-cooldown of %v", elapsed, cfg.BreakerCooldown)
+	}
+}
@@ -0,0 +1,11 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// Signals that resume a scan paused by -breaker before its cooldown ends
+var resumeSignals = []os.Signal{syscall.SIGUSR1}
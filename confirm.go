@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Target count above which a scan asks before starting, by default about a
+// /12 on one port
+const defaultConfirmLarge = 1 << 20
+
+// stdinIsTerminal reports whether someone can answer a prompt on stdin.
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// confirm writes prompt to w and reads the answer from r. Only "y" or "yes"
+// agree; anything else, including end of input, declines.
+func confirm(r io.Reader, w io.Writer, prompt string) bool {
+	fmt.Fprint(w, prompt)
+	line, _ := bufio.NewReader(r).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
+
+// confirmLargeScan stops a scan of more than -confirm-large-scan targets
+// unless -y was given or the user agrees at the terminal. Without a terminal
+// to ask on, -y is required. The prompt goes to stderr, out of the way of
+// -list-results-only pipelines.
+func confirmLargeScan(cfg *Config, total uint64, what string) {
+	if cfg.ConfirmLarge == 0 || total <= cfg.ConfirmLarge || cfg.Yes {
+		return
+	}
+	if !stdinIsTerminal() {
+		fmt.Printf("%s%s is %d targets, over -confirm-large-scan %d; pass -y to scan them without a terminal to confirm on%s\n",
+			ColorRed, what, total, cfg.ConfirmLarge, ColorReset)
+		os.Exit(1)
+	}
+	prompt := fmt.Sprintf("%s%s is %d targets. Scan them all? [y/N] %s", ColorYellow, what, total, ColorReset)
+	if !confirm(os.Stdin, os.Stderr, prompt) {
+		fmt.Printf("%sAborted%s\n", ColorRed, ColorReset)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		answer string
+		want   bool
+	}{
+		{"y\n", true},
+		{"YES\n", true},
+		{" yes \n", true},
+		{"\n", false},
+		{"n\n", false},
+		{"yep\n", false},
+		{"", false}, // stdin closed
+	}
+	for _, tt := range tests {
+		var prompt strings.Builder
+		if got := confirm(strings.NewReader(tt.answer), &prompt, "Scan? [y/N] "); got != tt.want {
+			t.Errorf("confirm(%q) = %v, want %v", tt.answer, got, tt.want)
+		}
+		if prompt.String() != "Scan? [y/N] " {
+			t.Errorf("confirm(%q) wrote %q, want the prompt", tt.answer, prompt.String())
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// envPrefix starts the name of every environment variable the scanner reads
+// its options from.
+const envPrefix = "SSH_SCANNER_"
+
+// envTargets holds the targets when no positional argument is given, the
+// environment counterpart of the CIDR argument.
+const envTargets = envPrefix + "TARGETS"
+
+// envAliases names the environment variables of the one- and two-letter
+// flags, which would read poorly as SSH_SCANNER_U or SSH_SCANNER_IL.
+var envAliases = map[string]string{
+	"u":  "USER",
+	"p":  "PASSWORD",
+	"w":  "WORKERS",
+	"t":  "TIMEOUT",
+	"P":  "PORT",
+	"o":  "OUTPUT",
+	"iL": "INPUT_FILE",
+	"i":  "KEY_FILE",
+}
+
+// envName returns the environment variable for the named flag: the prefix
+// followed by the upper-cased name with dashes as underscores, so
+// -hostkey-algos is SSH_SCANNER_HOSTKEY_ALGOS.
+func envName(flagName string) string {
+	if alias, ok := envAliases[flagName]; ok {
+		return envPrefix + alias
+	}
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// applyEnv sets the flags of fs that have a value in the environment, as
+// looked up by lookup, leaving alone the ones given on the command line so
+// they take precedence.
+func applyEnv(fs *flag.FlagSet, lookup func(string) (string, bool)) error {
+	given := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil || given[f.Name] {
+			return
+		}
+		name := envName(f.Name)
+		value, ok := lookup(name)
+		if !ok {
+			return
+		}
+		if setErr := fs.Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("%s: %v", name, setErr)
+		}
+	})
+	return err
+}
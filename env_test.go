@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"testing"
+	"time"
+)
+
+func TestApplyEnv(t *testing.T) {
+	env := map[string]string{
+		"SSH_SCANNER_USER":          "admin",
+		"SSH_SCANNER_TIMEOUT":       "5s",
+		"SSH_SCANNER_HOSTKEY_ALGOS": "ssh-ed25519",
+		"SSH_SCANNER_WORKERS":       "20",
+	}
+	lookup := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	user := fs.String("u", "test", "")
+	timeout := fs.Duration("t", 3*time.Second, "")
+	algos := fs.String("hostkey-algos", "", "")
+	workers := fs.String("w", "100", "")
+	port := fs.Int("P", 22, "")
+	if err := fs.Parse([]string{"-w", "50"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyEnv(fs, lookup); err != nil {
+		t.Fatal(err)
+	}
+
+	if *user != "admin" || *timeout != 5*time.Second || *algos != "ssh-ed25519" {
+		t.Errorf("env values not applied: -u %q -t %v -hostkey-algos %q", *user, *timeout, *algos)
+	}
+	if *workers != "50" {
+		t.Errorf("-w = %q, want the command line to beat SSH_SCANNER_WORKERS", *workers)
+	}
+	if *port != 22 {
+		t.Errorf("-P = %d, want the default with no variable set", *port)
+	}
+
+	env["SSH_SCANNER_PORT"] = "ssh"
+	if err := applyEnv(fs, lookup); err == nil {
+		t.Error("applyEnv() accepted an invalid SSH_SCANNER_PORT")
+	}
+}
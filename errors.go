@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"syscall"
+)
+
+// failureCategory buckets attempt errors by what they say about the target.
+type failureCategory int
+
+const (
+	failNone        failureCategory = iota
+	failTimeout                     // no answer in time: filtered port, dead host or tarpit
+	failRefused                     // host up, nothing listening
+	failUnreachable                 // no route from here
+	failDown                        // no reply to -ping, never dialled
+	failReset                       // connection dropped mid-handshake
+	failAuth                        // SSH server reached, credentials rejected
+	failHandshake                   // SSH server reached, negotiation failed
+	failNotSSH                      // port open, but the service is not SSH
+	failBanned                      // connections dropped after rejected passwords or accepted connects
+	failCancelled                   // aborted by cancelling the scan, never settled
+	failPanic                       // the attempt crashed; a bug, not a property of the target
+	failSkipped                     // not dialled, another port on the host was unreachable
+	failSampled                     // not dialled, enough hosts were found in the subnet
+	failUnmatched                   // SSH server reached, its banner did not match -banner-match
+	failKnown                       // not dialled, listed in the -o file by an earlier run
+	failOther
+	numFailureCategories
+)
+
+var failureNames = [numFailureCategories]string{
+	failNone:        "none",
+	failTimeout:     "timeout",
+	failRefused:     "refused",
+	failUnreachable: "unreachable",
+	failDown:        "down",
+	failReset:       "reset",
+	failAuth:        "auth",
+	failHandshake:   "handshake",
+	failNotSSH:      "not-ssh",
+	failBanned:      "banned",
+	failCancelled:   "cancelled",
+	failPanic:       "panic",
+	failSkipped:     "skipped",
+	failSampled:     "sampled",
+	failUnmatched:   "unmatched",
+	failKnown:       "known",
+	failOther:       "other",
+}
+
+func (c failureCategory) String() string {
+	return failureNames[c]
+}
+
+func (c failureCategory) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// Failures -retries requeues by default: the ones that may go away on their
+// own. A refused connection or rejected login is a definite answer.
+var defaultRetryOn = []failureCategory{failTimeout, failReset}
+
+// parseRetryOn parses a comma-separated -retry-on list of failure categories.
+// Only the ones that might clear up on a later attempt are accepted.
+func parseRetryOn(list string) ([]failureCategory, error) {
+	var retryOn []failureCategory
+	for _, name := range strings.Split(list, ",") {
+		switch name = strings.TrimSpace(name); name {
+		case "timeout":
+			retryOn = append(retryOn, failTimeout)
+		case "reset":
+			retryOn = append(retryOn, failReset)
+		case "unreachable":
+			retryOn = append(retryOn, failUnreachable)
+		case "refused", "auth":
+			return nil, fmt.Errorf("%s is a definite answer and never retried", name)
+		default:
+			return nil, fmt.Errorf("unknown category %q: use timeout, reset or unreachable", name)
+		}
+	}
+	return retryOn, nil
+}
+
+// failureSplit sums the failures into the ones where an SSH server was
+// reached and turned the login down, and the ones where there was none to
+// reach. Other categories count as neither.
+func failureSplit(counts map[failureCategory]int32) (rejected, dead int32) {
+	for c, n := range counts {
+		switch c {
+		case failAuth, failHandshake, failBanned:
+			rejected += n
+		case failTimeout, failRefused, failUnreachable, failDown, failSkipped:
+			dead += n
+		}
+	}
+	return rejected, dead
+}
+
+// reachedSSH reports whether a failed result came from an SSH server that
+// answered and then turned the attempt down, as opposed to no server at all.
+func reachedSSH(res Result) bool {
+	switch res.Category {
+	case failAuth, failHandshake, failBanned, failUnmatched:
+		return true
+	}
+	return res.Fingerprint != ""
+}
+
+// classifyError maps an attempt error to its category. crypto/ssh reports
+// authentication and negotiation failures as plain strings, so those are
+// matched on their text.
+func classifyError(err error) failureCategory {
+	switch {
+	case err == nil:
+		return failNone
+	case errors.Is(err, errHostDown):
+		return failDown
+	case errors.Is(err, errNotSSH):
+		return failNotSSH
+	case errors.Is(err, errBanned):
+		return failBanned
+	case errors.Is(err, context.Canceled):
+		return failCancelled
+	case errors.Is(err, errPanic):
+		return failPanic
+	case errors.Is(err, errPortSkipped):
+		return failSkipped
+	case errors.Is(err, errSubnetDone):
+		return failSampled
+	case errors.Is(err, errBannerMismatch):
+		return failUnmatched
+	case errors.Is(err, errKnownFound):
+		return failKnown
+	case isTimeout(err), errors.Is(err, context.DeadlineExceeded):
+		return failTimeout
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return failRefused
+	case isUnreachable(err):
+		return failUnreachable
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.ECONNABORTED), errors.Is(err, io.EOF):
+		return failReset
+	}
+
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "unable to authenticate"):
+		return failAuth
+	case strings.Contains(msg, "ssh: handshake failed"):
+		return failHandshake
+	}
+	return failOther
+}
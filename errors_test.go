@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestClassifyError(t *testing.T) {
+	dialErr := func(errno syscall.Errno) error {
+		return &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", errno)}
+	}
+
+	tests := []struct {
+		err  error
+		want failureCategory
+	}{
+		{nil, failNone},
+		{timeoutError{}, failTimeout},
+		{errHostDown, failDown},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: context.Canceled}, failCancelled},
+		{dialErr(syscall.ECONNREFUSED), failRefused},
+		{dialErr(syscall.ENETUNREACH), failUnreachable},
+		{dialErr(syscall.ECONNRESET), failReset},
+		{io.EOF, failReset},
+		{errors.New("ssh: handshake failed: ssh: unable to authenticate, attempted methods [none password], no supported methods remain"), failAuth},
+		{errors.New("ssh: handshake failed: ssh: no common algorithm for host key"), failHandshake},
+		{errors.New("something else"), failOther},
+	}
+	for _, tt := range tests {
+		if got := classifyError(tt.err); got != tt.want {
+			t.Errorf("classifyError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestFailureSplit(t *testing.T) {
+	counts := map[failureCategory]int32{failAuth: 3, failBanned: 1, failTimeout: 10, failRefused: 2, failReset: 4}
+	if rejected, dead := failureSplit(counts); rejected != 4 || dead != 12 {
+		t.Errorf("failureSplit() = %d rejected, %d dead; want 4 and 12", rejected, dead)
+	}
+}
+
+func TestReachedSSH(t *testing.T) {
+	tests := []struct {
+		res  Result
+		want bool
+	}{
+		{Result{Category: failAuth}, true},
+		{Result{Category: failHandshake}, true},
+		{Result{Category: failReset, Fingerprint: "SHA256:abc"}, true},
+		{Result{Category: failTimeout}, false},
+		{Result{Category: failRefused}, false},
+	}
+	for _, tt := range tests {
+		if got := reachedSSH(tt.res); got != tt.want {
+			t.Errorf("reachedSSH(%v, %q) = %v, want %v", tt.res.Category, tt.res.Fingerprint, got, tt.want)
+		}
+	}
+}
+
+func TestScannerCategorizesAuthFailure(t *testing.T) {
+	addr, _ := startTestServer(t, "toor")
+	host, portStr, _ := net.SplitHostPort(addr)
+	port, _ := strconv.Atoi(portStr)
+
+	cfg := &Config{Workers: 1, Port: port, User: "root", Password: "wrong", Timeout: time.Second}
+	s := NewScanner(cfg)
+	got := runScanner(s, host)
+
+	if len(got) != 1 || got[0].Category != failAuth {
+		t.Fatalf("Run() = %+v, want an auth failure", got)
+	}
+	if counts := s.FailureCounts(); counts[failAuth] != 1 {
+		t.Errorf("FailureCounts() = %v, want one auth failure", counts)
+	}
+	// The error is crypto/ssh's own, not a paraphrase
+	if want := "ssh: handshake failed: ssh: unable to authenticate"; !strings.HasPrefix(got[0].Error, want) {
+		t.Errorf("Error = %q, want it to start with %q", got[0].Error, want)
+	}
+}
+
+func TestScannerCancelAbortsAttempts(t *testing.T) {
+	// Accepts connections but never speaks, so only cancelling ends the handshake
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close() // Held open until the test ends
+		}
+	}()
+	host, portStr, _ := net.SplitHostPort(ln.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+
+	s := NewScanner(&Config{Workers: 2, Port: port, User: "root", Password: "toor", Timeout: time.Minute})
+	in := make(chan Target, 2)
+	in <- Target{Host: host}
+	in <- Target{Host: host}
+	close(in)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	out := make(chan Result)
+	go s.Run(ctx, in, out)
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	for res := range out {
+		t.Errorf("cancelled attempt reported: %+v", res)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Run took %v to stop after cancel", elapsed)
+	}
+	if stats := s.Stats(); stats.Cancelled != 2 || stats.Failed != 0 || stats.Processed != 0 {
+		t.Errorf("Stats() = %+v, want 2 cancelled and nothing processed", stats)
+	}
+}
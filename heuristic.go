@@ -0,0 +1,177 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Policies for -cred-policy, deciding what happens when a host drops the
+// connection after rejecting passwords
+const (
+	credExhaustive = "exhaustive"
+	credStopOnBan  = "stop-on-ban"
+)
+
+// Reconnects -cred-policy exhaustive makes after a drop before giving up
+const maxBanReconnects = 2
+
+// errPasswordsRejected ends tryPasswords once every password was turned down.
+// The wording matches crypto/ssh's own auth failures.
+var errPasswordsRejected = errors.New("ssh: unable to authenticate, every password was rejected")
+
+// errBanned marks hosts abandoned because they seem to have banned the
+// scanner: under -cred-policy stop-on-ban, or once they refuse connections
+// right after accepting them.
+var errBanned = errors.New("host stopped accepting connections, likely banned")
+
+// heuristicPasswords derives the weak passwords -heuristic-creds tries for
+// host: the address itself and its last octets, or the hostname and a few
+// common mutations of its first label.
+func heuristicPasswords(host string) []string {
+	if ip := net.ParseIP(host).To4(); ip != nil {
+		octets := strings.Split(ip.String(), ".")
+		return []string{
+			ip.String(),
+			strings.Join(octets, ""),
+			octets[3],
+			octets[2] + "." + octets[3],
+		}
+	}
+	if net.ParseIP(host) != nil {
+		return []string{host}
+	}
+
+	short, _, _ := strings.Cut(host, ".")
+	short = strings.ToLower(short)
+	if short == "" {
+		return []string{host}
+	}
+	return []string{
+		host,
+		short,
+		strings.ToUpper(short[:1]) + short[1:],
+		short + "123",
+		short + "@123",
+		short + "!",
+	}
+}
+
+// tryPasswords attempts each password in turn and returns the one that was
+// accepted. Several passwords share a connection, as far as the server's
+// MaxAuthTries allows; when it hangs up the remaining ones continue on a
+// new connection. Passwords are pulled from the sequence only as they are
+// sent, so -mangle variants are never held in memory all at once.
+//
+// A reset, refusal or timeout once passwords have been rejected looks like
+// a ban. -cred-policy stop-on-ban abandons the host right away; exhaustive
+// waits a timeout and reconnects, a few times, to get through the list.
+func (s *Scanner) tryPasswords(addr string, config *ssh.ClientConfig, passwords iter.Seq[string]) (string, error) {
+	pull, stop := iter.Pull(passwords)
+	defer stop()
+
+	// A password cut short by a hang-up, or looked ahead at, is sent next
+	var (
+		held    string
+		holding bool
+	)
+	take := func() (string, bool) {
+		if holding {
+			holding = false
+			return held, true
+		}
+		return pull()
+	}
+
+	rejected := 0
+	reconnects := 0
+	var lastErr error
+	for {
+		// Don't connect again only to find the list exhausted
+		if held, holding = take(); !holding {
+			if lastErr != nil {
+				// Keep what the server last said, for -v and -json
+				return "", fmt.Errorf("%w; last reply: %v", errPasswordsRejected, lastErr)
+			}
+			return "", errPasswordsRejected
+		}
+
+		before := rejected
+		sent, n := "", 0
+		config.Auth = []ssh.AuthMethod{ssh.RetryableAuthMethod(ssh.PasswordCallback(func() (string, error) {
+			// Being asked again means the previous one was rejected
+			if n > 0 {
+				rejected++
+			}
+			password, ok := take()
+			if !ok {
+				return "", errPasswordsRejected
+			}
+			sent = password
+			n++
+			s.passwordNum.Add(1)
+			return password, nil
+		}), 0)}
+
+		err := s.connect(addr, config)
+		s.passwordConnNum.Add(1)
+		if err == nil {
+			return sent, nil
+		}
+		if errors.Is(err, errPasswordsRejected) {
+			return "", err
+		}
+		lastErr = err
+
+		// An SSH disconnect, as sent on hitting MaxAuthTries, comes after the
+		// last password was judged. Any other hang-up may have cut it short,
+		// so that one is sent again.
+		disconnected := strings.Contains(err.Error(), "ssh: disconnect")
+		if n > 0 {
+			if disconnected {
+				rejected++
+			} else {
+				held, holding = sent, true
+			}
+		}
+		progress := rejected > before
+
+		if !disconnected && rejected > 0 && isBanDrop(err) {
+			if s.cfg.CredPolicy == credStopOnBan {
+				return "", fmt.Errorf("%w after %d rejected passwords: %v", errBanned, rejected, err)
+			}
+			if !progress {
+				if reconnects == maxBanReconnects {
+					return "", err
+				}
+				reconnects++
+				select {
+				case <-time.After(s.cfg.Timeout):
+				case <-s.ctx.Done():
+					return "", s.ctx.Err()
+				}
+				continue
+			}
+		}
+		if !progress {
+			// No password got a definite answer; the error isn't about them
+			return "", err
+		}
+		reconnects = 0
+	}
+}
+
+// isBanDrop reports whether err is the kind of hang-up a host gives once it
+// has banned the client, rather than a negotiation or auth failure.
+func isBanDrop(err error) bool {
+	switch classifyError(err) {
+	case failReset, failRefused, failTimeout, failBanned:
+		return true
+	}
+	return false
+}
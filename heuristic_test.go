@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"strconv"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestHeuristicPasswords(t *testing.T) {
+	tests := []struct {
+		host string
+		want []string
+	}{
+		{"10.1.2.3", []string{"10.1.2.3", "10123", "3", "2.3"}},
+		{"web01.example.com", []string{"web01.example.com", "web01", "Web01", "web01123", "web01@123", "web01!"}},
+		{"fe80::1", []string{"fe80::1"}},
+	}
+	for _, tt := range tests {
+		if got := heuristicPasswords(tt.host); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("heuristicPasswords(%q) = %q, want %q", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestScannerHeuristicCreds(t *testing.T) {
+	for _, tt := range []struct {
+		password string
+		found    bool
+	}{
+		{"1", true},  // last octet of 127.0.0.1, past MaxAuthTries
+		{"x", false}, // nothing derived matches
+	} {
+		var attempts atomic.Int32
+		addr, _ := startSSHServer(t, &ssh.ServerConfig{
+			MaxAuthTries: 2,
+			PasswordCallback: func(conn ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+				attempts.Add(1)
+				if string(pass) == tt.password {
+					return nil, nil
+				}
+				return nil, errors.New("access denied")
+			},
+		})
+		host, portStr, _ := net.SplitHostPort(addr)
+		port, _ := strconv.Atoi(portStr)
+
+		cfg := &Config{Workers: 1, Port: port, User: "root", Password: "toor", Timeout: time.Second, HeuristicCreds: true}
+		s := NewScanner(cfg)
+		got := runScanner(s, host)
+
+		if len(got) != 1 || got[0].Success != tt.found {
+			t.Fatalf("password %q: Run() = %+v, want success=%v", tt.password, got, tt.found)
+		}
+		if tt.found && got[0].Password != tt.password {
+			t.Errorf("password %q: Result.Password = %q", tt.password, got[0].Password)
+		}
+		if !tt.found && got[0].Category != failAuth {
+			t.Errorf("password %q: Category = %v, want auth", tt.password, got[0].Category)
+		}
+		// Each candidate is judged exactly once, across several connections
+		want := int32(5) // toor, 127.0.0.1, 127001, 1 and 0.1
+		if tt.found {
+			want = 4
+		}
+		if n := attempts.Load(); n != want {
+			t.Errorf("password %q: server saw %d attempts, want %d", tt.password, n, want)
+		}
+		// Two passwords per connection, as MaxAuthTries allows
+		if stats := s.Stats(); stats.Passwords != want || stats.PasswordConns != (want+1)/2 {
+			t.Errorf("password %q: Stats() counts %d passwords over %d connections, want %d over %d",
+				tt.password, stats.Passwords, stats.PasswordConns, want, (want+1)/2)
+		}
+	}
+}
+
+func TestScannerCredPolicy(t *testing.T) {
+	for _, tt := range []struct {
+		policy string
+		found  bool
+	}{
+		{credStopOnBan, false},
+		{credExhaustive, true},
+	} {
+		// After two rejections the host turns the next connection away
+		var rejected, blocked atomic.Int32
+		addr, _ := startSSHServer(t, &ssh.ServerConfig{
+			MaxAuthTries: 2,
+			PasswordCallback: func(conn ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+				if string(pass) == "1" {
+					return nil, nil
+				}
+				rejected.Add(1)
+				return nil, errors.New("access denied")
+			},
+		})
+		host, portStr, _ := net.SplitHostPort(addr)
+		port, _ := strconv.Atoi(portStr)
+
+		cfg := &Config{Workers: 1, Port: port, User: "root", Password: "toor", Timeout: 200 * time.Millisecond,
+			HeuristicCreds: true, CredPolicy: tt.policy}
+		s := NewScanner(cfg)
+		s.connect = func(addr string, config *ssh.ClientConfig) error {
+			if rejected.Load() >= 2 && blocked.Add(1) == 1 {
+				return &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNRESET}
+			}
+			return s.tryConnectSSH(addr, config)
+		}
+		got := runScanner(s, host)
+
+		if len(got) != 1 || got[0].Success != tt.found {
+			t.Fatalf("%s: Run() = %+v, want success=%v", tt.policy, got, tt.found)
+		}
+		if !tt.found && got[0].Category != failBanned {
+			t.Errorf("%s: Category = %v, want banned", tt.policy, got[0].Category)
+		}
+		if tt.found && got[0].Password != "1" {
+			t.Errorf("%s: Result.Password = %q, want 1", tt.policy, got[0].Password)
+		}
+	}
+}
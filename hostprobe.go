@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// errPortSkipped marks ports skipped by -connect-once.
+var errPortSkipped = errors.New("skipped: an earlier port on this host was unreachable")
+
+// errKnownFound marks targets skipped because -found-once found them before.
+var errKnownFound = errors.New("skipped: found by an earlier run")
+
+// errSubnetDone marks targets skipped by -max-found-per-subnet.
+var errSubnetDone = errors.New("skipped: enough hosts already found in this subnet")
+
+// hostProbe is the first attempt on a host under -connect-once. The host's
+// other ports wait for it and are skipped when it found the host unreachable.
+type hostProbe struct {
+	done    chan struct{}
+	dead    bool
+	pending int // targets on the host still to join
+}
+
+// hostProbes hands out one probe per host. The zero value is ready to use.
+type hostProbes struct {
+	mu     sync.Mutex
+	probes map[string]*hostProbe
+}
+
+// join returns the probe for host and whether the caller is to make it.
+// A host is forgotten once all of its ports have joined, so a sweep of a
+// large range only keeps the hosts in flight.
+func (hp *hostProbes) join(host string, ports int) (*hostProbe, bool) {
+	hp.mu.Lock()
+	defer hp.mu.Unlock()
+	if hp.probes == nil {
+		hp.probes = make(map[string]*hostProbe)
+	}
+	p, ok := hp.probes[host]
+	if !ok {
+		p = &hostProbe{done: make(chan struct{}), pending: ports}
+		hp.probes[host] = p
+	}
+	if p.pending--; p.pending <= 0 {
+		delete(hp.probes, host)
+	}
+	return p, !ok
+}
+
+// probeTarget attempts t, unless another port on the same host already
+// showed the host to be unreachable: timed out, without a route or down.
+// A refusal proves the host is up, so its other ports are still tried.
+func (s *Scanner) probeTarget(t Target, timeout time.Duration, res *Result) error {
+	p, first := s.probes.join(t.Host, max(len(s.cfg.Ports), 1))
+	if first {
+		defer close(p.done)
+		err := s.tryTarget(t, timeout, res)
+		switch classifyError(err) {
+		case failTimeout:
+			// A timeout on the fast pass of -fast-timeout proves nothing yet,
+			// and one in the login phase means the host answered
+			p.dead = timeout == s.cfg.Timeout && !errors.Is(err, errAuthTimeout)
+		case failUnreachable, failDown:
+			p.dead = true
+		}
+		return err
+	}
+
+	select {
+	case <-p.done:
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	}
+	if p.dead {
+		res.skipped = true
+		return errPortSkipped
+	}
+	return s.tryTarget(t, timeout, res)
+}
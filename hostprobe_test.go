@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestScannerConnectOnce(t *testing.T) {
+	ports := []int{22, 2222, 2022}
+	cfg := &Config{Workers: 8, Ports: ports, Timeout: time.Second, ConnectOnce: true}
+	s := NewScanner(cfg)
+
+	// 10.0.0.1 is filtered, 10.0.0.2 is up with nothing listening
+	var dialled atomic.Int32
+	s.connect = func(addr string, config *ssh.ClientConfig) error {
+		dialled.Add(1)
+		if strings.HasPrefix(addr, "10.0.0.1:") {
+			time.Sleep(10 * time.Millisecond)
+			return timeoutError{}
+		}
+		return &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+	}
+
+	targets, _, err := rangeTargets([]string{"10.0.0.1", "10.0.0.2"}, ports, cfg.bufferSize())
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make(chan Result)
+	go s.Run(context.Background(), targets, out)
+	for range out {
+	}
+
+	if n := dialled.Load(); n != 4 {
+		t.Errorf("%d ports dialled, want 1 on the filtered host and 3 on the refusing one", n)
+	}
+	counts := s.FailureCounts()
+	if counts[failSkipped] != 2 || counts[failTimeout] != 1 || counts[failRefused] != 3 {
+		t.Errorf("FailureCounts() = %v, want 2 skipped, 1 timeout, 3 refused", counts)
+	}
+	if n := s.Stats().Skipped; n != 2 {
+		t.Errorf("Stats().Skipped = %d, want 2", n)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"math"
+	"net"
+)
+
+// IPIterator enumerates the addresses of a network in order, the network
+// and broadcast addresses included, as some routers answer on them. It is
+// what generateIPs streams from, for callers that want to drive the
+// enumeration themselves and know exactly how far along it is.
+//
+// An IPIterator is not safe for concurrent use.
+type IPIterator struct {
+	next      net.IP
+	ipNet     *net.IPNet
+	remaining uint64
+}
+
+// NewIPIterator returns an iterator over the network ip belongs to in
+// ipNet, starting from its first address whatever ip's host bits are.
+func NewIPIterator(ip net.IP, ipNet *net.IPNet) *IPIterator {
+	// Work with 4-byte IPs for IPv4 so the mask lines up
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	// A copy, as Next increments it in place
+	first := make(net.IP, len(ip))
+	copy(first, ip.Mask(ipNet.Mask))
+
+	it := &IPIterator{next: first, ipNet: ipNet, remaining: math.MaxUint64}
+	// Above 2^64 addresses, Remaining stays at its maximum
+	if ones, bits := ipNet.Mask.Size(); bits-ones < 64 {
+		it.remaining = countIPs(ipNet)
+	}
+	return it
+}
+
+// Next returns the next address, or false once all of them were returned.
+// The returned IP is the caller's to keep.
+func (it *IPIterator) Next() (net.IP, bool) {
+	if it.remaining == 0 || !it.ipNet.Contains(it.next) {
+		return nil, false
+	}
+	ip := make(net.IP, len(it.next))
+	copy(ip, it.next)
+	inc(it.next)
+	if it.remaining != math.MaxUint64 {
+		it.remaining--
+	}
+	return ip, true
+}
+
+// Remaining returns the number of addresses Next has yet to return, or the
+// largest uint64 for IPv6 ranges of 2^64 addresses or more.
+func (it *IPIterator) Remaining() uint64 {
+	return it.remaining
+}
+
+func inc(ip net.IP) {
+	for j := len(ip) - 1; j >= 0; j-- {
+		ip[j]++
+		if ip[j] > 0 {
+			break
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"math"
+	"net"
+	"testing"
+)
+
+func TestIPIterator(t *testing.T) {
+	ip, ipNet, _ := net.ParseCIDR("10.0.0.5/30")
+	it := NewIPIterator(ip, ipNet)
+
+	var got []string
+	for want := uint64(4); ; want-- {
+		if it.Remaining() != want {
+			t.Fatalf("Remaining() = %d after %v, want %d", it.Remaining(), got, want)
+		}
+		next, ok := it.Next()
+		if !ok {
+			break
+		}
+		got = append(got, next.String())
+	}
+	if len(got) != 4 || got[0] != "10.0.0.4" || got[3] != "10.0.0.7" {
+		t.Errorf("Next() gave %v, want 10.0.0.4 to 10.0.0.7", got)
+	}
+	if _, ok := it.Next(); ok {
+		t.Error("Next() returned an address after the end")
+	}
+}
+
+func TestIPIteratorEdges(t *testing.T) {
+	// The last address must end the range rather than wrap around to 0.0.0.0
+	_, ipNet, _ := net.ParseCIDR("255.255.255.254/31")
+	it := NewIPIterator(ipNet.IP, ipNet)
+	first, _ := it.Next()
+	it.Next()
+	if _, ok := it.Next(); ok {
+		t.Error("Next() went past 255.255.255.255")
+	}
+	// Returned addresses don't change as the iterator moves on
+	if first.String() != "255.255.255.254" {
+		t.Errorf("first address %s, want 255.255.255.254", first)
+	}
+
+	_, big, _ := net.ParseCIDR("2001:db8::/48")
+	if got := NewIPIterator(big.IP, big).Remaining(); got != math.MaxUint64 {
+		t.Errorf("Remaining() for a /48 = %d, want the maximum", got)
+	}
+}
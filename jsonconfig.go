@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// jobSpec is the scan description accepted by -json-config. Fields left out
+// keep the value from the command line flags.
+type jobSpec struct {
+	Targets   []string `json:"targets"`
+	InputFile string   `json:"input_file"`
+
+	User     *string `json:"user"`
+	Password *string `json:"password"`
+	KeyFile  *string `json:"key_file"`
+	CertFile *string `json:"cert_file"`
+
+	Port  *int  `json:"port"`
+	Ports []int `json:"ports"`
+
+	Workers       *int    `json:"workers"`
+	Retries       *int    `json:"retries"`
+	PerSubnet     *int    `json:"per_subnet"`
+	Timeout       *string `json:"timeout"`
+	FastTimeout   *string `json:"fast_timeout"`
+	Ramp          *string `json:"ramp"`
+	Precheck      *string `json:"precheck"`
+	Ping          *string `json:"ping"`
+	Window        *string `json:"window"`
+	BannerTimeout *string `json:"banner_timeout"`
+	AuthTimeout   *string `json:"auth_timeout"`
+
+	HostKeyAlgorithms  []string `json:"hostkey_algos"`
+	OutputFile         *string  `json:"output"`
+	Tag                *string  `json:"tag"`
+	DedupByFingerprint *bool    `json:"dedup_by_fingerprint"`
+	TrustReachable     *bool    `json:"trust_reachable"`
+	DetectSSH          *bool    `json:"detect_ssh"`
+}
+
+// loadJSONConfig reads a jobSpec from path ("-" for stdin), validates it and
+// applies it to cfg. hostKeyAlgos receives the algorithm list so it goes
+// through the same validation as the -hostkey-algos flag.
+func loadJSONConfig(cfg *Config, path string, hostKeyAlgos *string) error {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var spec jobSpec
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&spec); err != nil {
+		return err
+	}
+	return spec.apply(cfg, hostKeyAlgos)
+}
+
+func (spec *jobSpec) apply(cfg *Config, hostKeyAlgos *string) error {
+	if (len(spec.Targets) == 0) == (spec.InputFile == "") {
+		return errors.New("exactly one of \"targets\" or \"input_file\" is required")
+	}
+	for i, target := range spec.Targets {
+		for _, sub := range expandShortcut(target) {
+			if _, _, err := parseInput(sub); err != nil {
+				return fmt.Errorf("targets[%d]: %v", i, err)
+			}
+		}
+	}
+	for i, port := range spec.Ports {
+		if port < 1 || port > 65535 {
+			return fmt.Errorf("ports[%d]: %d out of range", i, port)
+		}
+	}
+	if spec.Port != nil && (*spec.Port < 1 || *spec.Port > 65535) {
+		return fmt.Errorf("port: %d out of range", *spec.Port)
+	}
+	if spec.Workers != nil && *spec.Workers < 1 {
+		return errors.New("workers must be positive")
+	}
+	if spec.Retries != nil && *spec.Retries < 0 {
+		return errors.New("retries must not be negative")
+	}
+	if spec.PerSubnet != nil && *spec.PerSubnet < 0 {
+		return errors.New("per_subnet must not be negative")
+	}
+
+	// The same bounds parseConfig puts on the flags: a timeout is required,
+	// the rest may be 0 for off
+	durations := []struct {
+		name     string
+		value    *string
+		dst      *time.Duration
+		required bool
+	}{
+		{"timeout", spec.Timeout, &cfg.Timeout, true},
+		{"fast_timeout", spec.FastTimeout, &cfg.FastTimeout, false},
+		{"ramp", spec.Ramp, &cfg.Ramp, false},
+		{"precheck", spec.Precheck, &cfg.Precheck, false},
+		{"ping", spec.Ping, &cfg.Ping, false},
+		{"banner_timeout", spec.BannerTimeout, &cfg.BannerTimeout, false},
+		{"auth_timeout", spec.AuthTimeout, &cfg.AuthTimeout, false},
+	}
+	for _, d := range durations {
+		if d.value == nil {
+			continue
+		}
+		v, err := time.ParseDuration(*d.value)
+		switch {
+		case err != nil:
+			return fmt.Errorf("%s: %v", d.name, err)
+		case d.required && v <= 0:
+			return fmt.Errorf("%s must be positive", d.name)
+		case v < 0:
+			return fmt.Errorf("%s must not be negative", d.name)
+		}
+		*d.dst = v
+	}
+
+	if spec.Window != nil {
+		w, err := parseWindow(*spec.Window)
+		if err != nil {
+			return fmt.Errorf("window: %v", err)
+		}
+		cfg.Window = w
+	}
+
+	cfg.Targets = spec.Targets
+	cfg.Ports = spec.Ports
+	if spec.InputFile != "" {
+		cfg.InputFile = spec.InputFile
+	}
+	setIf(&cfg.User, spec.User)
+	setIf(&cfg.Password, spec.Password)
+	setIf(&cfg.KeyFile, spec.KeyFile)
+	setIf(&cfg.CertFile, spec.CertFile)
+	setIf(&cfg.Port, spec.Port)
+	setIf(&cfg.Workers, spec.Workers)
+	setIf(&cfg.Retries, spec.Retries)
+	setIf(&cfg.PerSubnet, spec.PerSubnet)
+	setIf(&cfg.OutputFile, spec.OutputFile)
+	setIf(&cfg.Tag, spec.Tag)
+	setIf(&cfg.DedupByFingerprint, spec.DedupByFingerprint)
+	setIf(&cfg.TrustReachable, spec.TrustReachable)
+	setIf(&cfg.DetectSSH, spec.DetectSSH)
+	if len(spec.HostKeyAlgorithms) > 0 {
+		*hostKeyAlgos = strings.Join(spec.HostKeyAlgorithms, ",")
+	}
+	return nil
+}
+
+func setIf[T any](dst *T, v *T) {
+	if v != nil {
+		*dst = *v
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJobSpecApply(t *testing.T) {
+	cfg := &Config{User: "test", Password: "123456", Port: 22, Workers: 100, Timeout: 3 * time.Second}
+	var algos string
+
+	user, timeout := "root", "1500ms"
+	spec := jobSpec{
+		Targets:           []string{"10.0.0.0/30", "3"},
+		User:              &user,
+		Ports:             []int{22, 2222},
+		Timeout:           &timeout,
+		HostKeyAlgorithms: []string{"ssh-rsa", "ssh-dss"},
+	}
+	if err := spec.apply(cfg, &algos); err != nil {
+		t.Fatalf("apply() error = %v", err)
+	}
+
+	if cfg.User != "root" || cfg.Password != "123456" || cfg.Timeout != 1500*time.Millisecond {
+		t.Errorf("apply() = %+v, want spec values over flag values", cfg)
+	}
+	if !reflect.DeepEqual(cfg.Ports, []int{22, 2222}) || algos != "ssh-rsa,ssh-dss" {
+		t.Errorf("apply() ports = %v, algos = %q", cfg.Ports, algos)
+	}
+
+	invalid := []string{
+		`{}`,
+		`{"targets":["nope"]}`,
+		`{"targets":["10.0.0.1"],"ports":[0]}`,
+		`{"targets":["10.0.0.1"],"timeout":"soon"}`,
+		`{"targets":["10.0.0.1"],"timeout":"0s"}`,
+		`{"targets":["10.0.0.1"],"ramp":"-1s"}`,
+		`{"targets":["10.0.0.1"],"per_subnet":-1}`,
+		`{"targets":["10.0.0.1"],"input_file":"hosts.txt"}`,
+	}
+	for _, in := range invalid {
+		var spec jobSpec
+		if err := jsonDecodeStrict(in, &spec); err != nil {
+			continue
+		}
+		if err := spec.apply(&Config{}, &algos); err == nil {
+			t.Errorf("apply(%s) expected error", in)
+		}
+	}
+}
+
+func jsonDecodeStrict(in string, v any) error {
+	dec := json.NewDecoder(strings.NewReader(in))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
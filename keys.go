@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// loadSigner reads an unencrypted private key and, if certFile is set, wraps
+// it with the matching OpenSSH user certificate.
+func loadSigner(keyFile, certFile string) (ssh.Signer, error) {
+	keyBytes, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := ssh.ParsePrivateKey(keyBytes)
+	if err != nil {
+		var missing *ssh.PassphraseMissingError
+		if errors.As(err, &missing) {
+			return nil, fmt.Errorf("%s is passphrase protected; decrypt it first", keyFile)
+		}
+		return nil, fmt.Errorf("%s: %v", keyFile, err)
+	}
+	if certFile == "" {
+		return signer, nil
+	}
+
+	certBytes, err := os.ReadFile(certFile)
+	if err != nil {
+		return nil, err
+	}
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(certBytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", certFile, err)
+	}
+	cert, ok := pub.(*ssh.Certificate)
+	if !ok {
+		return nil, fmt.Errorf("%s is a plain public key, not a certificate", certFile)
+	}
+	if cert.CertType != ssh.UserCert {
+		return nil, fmt.Errorf("%s is a host certificate, not a user certificate", certFile)
+	}
+
+	// NewCertSigner rejects a certificate issued for a different key
+	return ssh.NewCertSigner(cert, signer)
+}
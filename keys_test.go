@@ -0,0 +1,71 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// writeKey stores a fresh ed25519 private key in dir and returns its path and signer.
+func writeKey(t *testing.T, dir, name string) (string, ssh.Signer) {
+	t.Helper()
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path, signer
+}
+
+func TestLoadSignerCertificate(t *testing.T) {
+	dir := t.TempDir()
+	keyFile, userKey := writeKey(t, dir, "id_ed25519")
+	_, caKey := writeKey(t, dir, "ca")
+	_, otherKey := writeKey(t, dir, "other")
+
+	issue := func(name string, key ssh.PublicKey) string {
+		cert := &ssh.Certificate{
+			Key:             key,
+			CertType:        ssh.UserCert,
+			ValidPrincipals: []string{"root"},
+			ValidBefore:     ssh.CertTimeInfinity,
+		}
+		if err := cert.SignCert(rand.Reader, caKey); err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, ssh.MarshalAuthorizedKey(cert), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	signer, err := loadSigner(keyFile, issue("id_ed25519-cert.pub", userKey.PublicKey()))
+	if err != nil {
+		t.Fatalf("loadSigner() error = %v", err)
+	}
+	if _, ok := signer.PublicKey().(*ssh.Certificate); !ok {
+		t.Errorf("loadSigner() public key is %T, want *ssh.Certificate", signer.PublicKey())
+	}
+
+	if _, err := loadSigner(keyFile, issue("other-cert.pub", otherKey.PublicKey())); err == nil {
+		t.Errorf("loadSigner() with a certificate for another key expected error")
+	}
+}
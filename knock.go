@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// knockStep is one port of a -knock sequence.
+type knockStep struct {
+	network string // "tcp" or "udp"
+	port    int
+}
+
+// knockSequence is the -knock sequence sent to each host before its SSH
+// attempt, for hosts that only open the SSH port to addresses that knocked.
+type knockSequence []knockStep
+
+func (k knockSequence) String() string {
+	steps := make([]string, len(k))
+	for i, step := range k {
+		steps[i] = step.network + "/" + strconv.Itoa(step.port)
+	}
+	return strings.Join(steps, ",")
+}
+
+// parseKnock parses a comma-separated -knock list such as
+// "tcp/1000,udp/2000,3000". A port without a protocol is knocked over TCP.
+func parseKnock(list string) (knockSequence, error) {
+	var k knockSequence
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		network, portStr, ok := strings.Cut(item, "/")
+		if !ok {
+			network, portStr = "tcp", item
+		}
+		network = strings.ToLower(network)
+		if network != "tcp" && network != "udp" {
+			return nil, fmt.Errorf("unknown protocol %q in %q: use tcp or udp", network, item)
+		}
+		port, err := parsePort(portStr)
+		if err != nil {
+			return nil, err
+		}
+		k = append(k, knockStep{network, port})
+	}
+	return k, nil
+}
+
+// knock sends the -knock sequence to host: a TCP connect, dropped after
+// -knock-delay whether or not it was answered, or an empty UDP datagram per
+// step, then -knock-delay of quiet so the firewall can open up before the
+// next step or the SSH attempt. The knocks come from the source address the
+// SSH attempt uses; -knock takes at most one -source-ips address.
+func (s *Scanner) knock(host string) error {
+	delay := s.cfg.KnockDelay
+	for _, step := range s.cfg.Knock {
+		addr := net.JoinHostPort(host, strconv.Itoa(step.port))
+		d := &net.Dialer{Timeout: delay}
+		if src := s.sourceAddr(host); src != nil {
+			d.LocalAddr = src
+			if step.network == "udp" {
+				d.LocalAddr = &net.UDPAddr{IP: src.IP}
+			}
+		}
+
+		// Refused and unanswered connects are what a knock usually gets
+		conn, err := d.DialContext(s.ctx, step.network, addr)
+		if err == nil {
+			if step.network == "udp" {
+				conn.Write(nil)
+			}
+			conn.Close()
+		}
+		if err := sleepCtx(s.ctx, delay); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sleepCtx waits for d or until ctx is done, whichever comes first.
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseKnock(t *testing.T) {
+	got, err := parseKnock("tcp/1000, UDP/2000,3000")
+	if err != nil {
+		t.Fatalf("parseKnock() error = %v", err)
+	}
+	want := knockSequence{{"tcp", 1000}, {"udp", 2000}, {"tcp", 3000}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseKnock() = %v, want %v", got, want)
+	}
+	if got.String() != "tcp/1000,udp/2000,tcp/3000" {
+		t.Errorf("String() = %q", got.String())
+	}
+
+	for _, bad := range []string{"icmp/1000", "tcp/0", "tcp/", "", "1000,,2000"} {
+		if _, err := parseKnock(bad); err == nil {
+			t.Errorf("parseKnock(%q) expected error", bad)
+		}
+	}
+}
+
+func TestScannerKnock(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		heard []string
+	)
+	note := func(what string) {
+		mu.Lock()
+		heard = append(heard, what)
+		mu.Unlock()
+	}
+
+	tcp1, _ := net.Listen("tcp", "127.0.0.1:0")
+	tcp2, _ := net.Listen("tcp", "127.0.0.1:0")
+	udp, _ := net.ListenPacket("udp", "127.0.0.1:0")
+	defer tcp1.Close()
+	defer tcp2.Close()
+	defer udp.Close()
+	for name, ln := range map[string]net.Listener{"tcp1": tcp1, "tcp2": tcp2} {
+		go func() {
+			for {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				note(name)
+				conn.Close()
+			}
+		}()
+	}
+	go func() {
+		buf := make([]byte, 16)
+		for {
+			if _, _, err := udp.ReadFrom(buf); err != nil {
+				return
+			}
+			note("udp")
+		}
+	}()
+
+	cfg := &Config{KnockDelay: 20 * time.Millisecond, Knock: knockSequence{
+		{"tcp", tcp1.Addr().(*net.TCPAddr).Port},
+		{"udp", udp.LocalAddr().(*net.UDPAddr).Port},
+		{"tcp", tcp2.Addr().(*net.TCPAddr).Port},
+	}}
+	if err := NewScanner(cfg).knock("127.0.0.1"); err != nil {
+		t.Fatalf("knock() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []string{"tcp1", "udp", "tcp2"}; !reflect.DeepEqual(heard, want) {
+		t.Errorf("knocks heard %v, want %v", heard, want)
+	}
+}
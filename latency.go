@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Upper bounds of the latency buckets, in a 1-2-5 series. Attempts slower
+// than the last one land in an extra overflow bucket.
+var latencyBounds = [...]time.Duration{
+	time.Millisecond, 2 * time.Millisecond, 5 * time.Millisecond,
+	10 * time.Millisecond, 20 * time.Millisecond, 50 * time.Millisecond,
+	100 * time.Millisecond, 200 * time.Millisecond, 500 * time.Millisecond,
+	time.Second, 2 * time.Second, 5 * time.Second,
+	10 * time.Second, 20 * time.Second, 50 * time.Second,
+	100 * time.Second,
+}
+
+// latencyHistogram counts attempt durations in fixed buckets. Workers add to
+// it concurrently without locking, and it can be read while they do.
+type latencyHistogram struct {
+	counts [len(latencyBounds) + 1]atomic.Int64 // one per bound, then the overflow
+}
+
+func (h *latencyHistogram) add(d time.Duration) {
+	i := 0
+	for i < len(latencyBounds) && d > latencyBounds[i] {
+		i++
+	}
+	h.counts[i].Add(1)
+}
+
+// percentile returns the bucket the fraction q of the attempts falls within,
+// as an index into latencyBounds; len(latencyBounds) is the overflow. ok is
+// false while nothing has been recorded.
+func (h *latencyHistogram) percentile(q float64) (bucket int, ok bool) {
+	var counts [len(h.counts)]int64
+	var total int64
+	for i := range h.counts {
+		counts[i] = h.counts[i].Load()
+		total += counts[i]
+	}
+	if total == 0 {
+		return 0, false
+	}
+
+	rank := max(1, int64(q*float64(total)+0.999999))
+	var seen int64
+	for i, n := range counts {
+		if seen += n; seen >= rank {
+			return i, true
+		}
+	}
+	return len(latencyBounds), true
+}
+
+// latencyLabel describes a bucket from percentile as the bound the
+// attempts stayed within.
+func latencyLabel(bucket int) string {
+	if bucket >= len(latencyBounds) {
+		return fmt.Sprintf(">%v", latencyBounds[len(latencyBounds)-1])
+	}
+	return fmt.Sprintf("≤%v", latencyBounds[bucket])
+}
+
+// latencySummary formats the p50, p90 and p99 attempt durations for the
+// scan summary, or "" when no attempt was timed.
+func latencySummary(h *latencyHistogram) string {
+	var parts []string
+	for _, p := range []struct {
+		name string
+		q    float64
+	}{{"p50", 0.5}, {"p90", 0.9}, {"p99", 0.99}} {
+		bucket, ok := h.percentile(p.q)
+		if !ok {
+			return ""
+		}
+		parts = append(parts, p.name+" "+latencyLabel(bucket))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLatencyHistogram(t *testing.T) {
+	var h latencyHistogram
+	if got := latencySummary(&h); got != "" {
+		t.Errorf("latencySummary(empty) = %q, want \"\"", got)
+	}
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 89 {
+				h.add(150 * time.Millisecond)
+			}
+			for range 10 {
+				h.add(3 * time.Second)
+			}
+			h.add(5 * time.Minute)
+		}()
+	}
+	wg.Wait()
+
+	if got, want := latencySummary(&h), "p50 ≤200ms, p90 ≤5s, p99 ≤5s"; got != want {
+		t.Errorf("latencySummary() = %q, want %q", got, want)
+	}
+	if bucket, _ := h.percentile(1); latencyLabel(bucket) != ">1m40s" {
+		t.Errorf("p100 = %s, want the overflow bucket", latencyLabel(bucket))
+	}
+	if bucket, _ := h.percentile(0); latencyLabel(bucket) != "≤200ms" {
+		t.Errorf("p0 = %s, want the fastest attempts' bucket", latencyLabel(bucket))
+	}
+}
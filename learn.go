@@ -0,0 +1,60 @@
+package main
+
+import (
+	"cmp"
+	"iter"
+	"slices"
+	"sync"
+)
+
+// Number of passwords that worked elsewhere tried first on each host by
+// -learn-creds
+const learnedTop = 5
+
+// passwordHits counts the hosts each password was accepted on during a run.
+// The zero value is ready to use.
+type passwordHits struct {
+	mu   sync.Mutex
+	hits map[string]int
+}
+
+func (h *passwordHits) add(password string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.hits == nil {
+		h.hits = make(map[string]int)
+	}
+	h.hits[password]++
+}
+
+// top returns up to n passwords, the most accepted first.
+func (h *passwordHits) top(n int) []string {
+	h.mu.Lock()
+	passwords := make([]string, 0, len(h.hits))
+	for password := range h.hits {
+		passwords = append(passwords, password)
+	}
+	slices.SortFunc(passwords, func(a, b string) int {
+		return cmp.Or(cmp.Compare(h.hits[b], h.hits[a]), cmp.Compare(a, b))
+	})
+	h.mu.Unlock()
+
+	return passwords[:min(n, len(passwords))]
+}
+
+// learnedFirst yields the learned passwords, then the rest of passwords
+// without the ones already yielded.
+func learnedFirst(learned []string, passwords iter.Seq[string]) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for _, password := range learned {
+			if !yield(password) {
+				return
+			}
+		}
+		for password := range passwords {
+			if !slices.Contains(learned, password) && !yield(password) {
+				return
+			}
+		}
+	}
+}
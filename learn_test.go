@@ -0,0 +1,29 @@
+package main
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestPasswordHitsTop(t *testing.T) {
+	var h passwordHits
+	if got := h.top(learnedTop); len(got) != 0 {
+		t.Errorf("top() before any hit = %v, want none", got)
+	}
+	for _, password := range []string{"toor", "admin", "toor", "winter", "admin", "toor"} {
+		h.add(password)
+	}
+	if got, want := h.top(2), []string{"toor", "admin"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("top(2) = %v, want %v", got, want)
+	}
+}
+
+func TestLearnedFirst(t *testing.T) {
+	passwords := slices.Values([]string{"root", "admin", "123456", "toor"})
+	got := slices.Collect(learnedFirst([]string{"toor", "Winter2026"}, passwords))
+	want := []string{"toor", "Winter2026", "root", "admin", "123456"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("learnedFirst() = %v, want %v", got, want)
+	}
+}
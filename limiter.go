@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"net"
+	"sync"
+)
+
+// Limiter bounds the number of connection attempts in flight. Each Scanner
+// holds its attempts to Workers with a Limiter of its own; one set as
+// Config.Limiter is shared on top of that, so several scanners running at
+// once stay within a single total budget, such as an egress connection
+// limit. The contract for a shared Limiter:
+//
+//   - it is safe for concurrent use by any number of scanners and Runs, and
+//     is never closed or resized by them, so it can outlive a scan;
+//   - a scanner takes a slot of its own before a shared one, so it holds at
+//     most Workers shared slots and waits for them like any other scanner;
+//     the order among waiting scanners is not fair, only bounded;
+//   - a slot is held only while attempts run, pre-checks, banners and
+//     retries included, and released when they end or are cancelled; under
+//     -per-subnet a worker may go straight on to a target parked in the
+//     same subnet without giving its slot back in between;
+//   - callers that take slots themselves must pair each successful Acquire
+//     with one Release.
+type Limiter struct {
+	slots chan struct{}
+}
+
+// NewLimiter returns a Limiter with n slots.
+func NewLimiter(n int) *Limiter {
+	return &Limiter{slots: make(chan struct{}, n)}
+}
+
+// Acquire blocks until a slot is free or ctx is done, returning ctx.Err() in
+// the latter case.
+func (l *Limiter) Acquire(ctx context.Context) error {
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Release frees a slot taken by Acquire.
+func (l *Limiter) Release() {
+	<-l.slots
+}
+
+// InUse returns the number of slots currently held.
+func (l *Limiter) InUse() int {
+	return len(l.slots)
+}
+
+// Upper bound on targets parked by subnetLimiter, beyond which the
+// dispatcher waits for a slot instead
+const maxParked = 1 << 16
+
+// subnetLimiter caps concurrent attempts per subnet (/24 for IPv4, /64 for
+// IPv6) so a single upstream router never sees more than limit connections
+// from us at once, however many workers are running overall.
+//
+// Targets usually arrive a subnet at a time, so waiting for a full subnet
+// would hold the whole scan to limit attempts. A target whose subnet is full
+// is parked instead, and the attempt that frees the next slot there takes it
+// over, while the dispatcher moves on to other subnets.
+type subnetLimiter struct {
+	limit int
+
+	mu        sync.Mutex
+	inUse     map[string]int
+	parked    map[string][]Target
+	numParked int
+	freed     chan struct{} // closed and replaced whenever a slot is freed
+}
+
+func newSubnetLimiter(limit int) *subnetLimiter {
+	return &subnetLimiter{
+		limit:  limit,
+		inUse:  make(map[string]int),
+		parked: make(map[string][]Target),
+		freed:  make(chan struct{}),
+	}
+}
+
+// acquire takes a slot in t's subnet and returns the key to pass to release.
+// When the subnet is full, t is parked and ok is false: it is handed out by
+// release later. Only with maxParked targets parked already does acquire
+// wait for a slot, or until ctx is done.
+func (l *subnetLimiter) acquire(ctx context.Context, t Target) (key string, ok bool, err error) {
+	key = subnetKey(t.Host)
+
+	l.mu.Lock()
+	for {
+		switch {
+		case l.inUse[key] < l.limit:
+			l.inUse[key]++
+			l.mu.Unlock()
+			return key, true, nil
+		case l.numParked < maxParked:
+			l.parked[key] = append(l.parked[key], t)
+			l.numParked++
+			l.mu.Unlock()
+			return key, false, nil
+		}
+		freed := l.freed
+		l.mu.Unlock()
+		select {
+		case <-freed:
+		case <-ctx.Done():
+			return "", false, ctx.Err()
+		}
+		l.mu.Lock()
+	}
+}
+
+// release gives back a slot taken for key. With handOff set, a target
+// parked in that subnet keeps the slot instead and is returned for the
+// caller to attempt next; without it, as when the scan is stopping, the
+// targets parked there are dropped.
+func (l *subnetLimiter) release(key string, handOff bool) (next Target, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if queue := l.parked[key]; len(queue) > 0 {
+		if handOff {
+			next = queue[0]
+			if l.parked[key] = queue[1:]; len(queue) == 1 {
+				delete(l.parked, key)
+			}
+			l.numParked--
+			return next, true
+		}
+		delete(l.parked, key)
+		l.numParked -= len(queue)
+	}
+	if l.inUse[key]--; l.inUse[key] == 0 {
+		// Keep the map proportional to the subnets in flight, not all seen
+		delete(l.inUse, key)
+	}
+	close(l.freed)
+	l.freed = make(chan struct{})
+	return Target{}, false
+}
+
+// subnetKey groups a host with its neighbours. Hostnames are their own group.
+func subnetKey(host string) string {
+	ip := net.ParseIP(host)
+	switch {
+	case ip == nil:
+		return host
+	case ip.To4() != nil:
+		return ip.Mask(net.CIDRMask(24, 32)).String()
+	default:
+		return ip.Mask(net.CIDRMask(64, 128)).String()
+	}
+}
+
+// subnetQuota counts the hosts found per subnet, so -max-found-per-subnet
+// can skip the rest of a subnet once it has proven the credentials work
+// there. The zero value is ready to use.
+type subnetQuota struct {
+	mu    sync.Mutex
+	found map[string]int
+}
+
+func (q *subnetQuota) add(host string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.found == nil {
+		q.found = make(map[string]int)
+	}
+	q.found[subnetKey(host)]++
+}
+
+// full reports whether host's subnet already has limit hosts found.
+func (q *subnetQuota) full(host string, limit int) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.found[subnetKey(host)] >= limit
+}
@@ -1,9 +1,18 @@
 package main
 
 import (
+	"io"
 	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
 	"reflect"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestGenerateIPs(t *testing.T) {
@@ -66,6 +75,48 @@ func TestParseInput(t *testing.T) {
 			wantErr:  false,
 			expected: "192.168.3.0/24",
 		},
+		{
+			input:    "::ffff:10.0.0.1",
+			wantErr:  false,
+			expected: "10.0.0.1/32",
+		},
+		{
+			input:    "::ffff:192.168.1.0/120",
+			wantErr:  false,
+			expected: "192.168.1.0/24",
+		},
+		{
+			input:    "3232235777",
+			wantErr:  false,
+			expected: "192.168.1.1/32",
+		},
+		{
+			input:    "0xC0A80100/24",
+			wantErr:  false,
+			expected: "192.168.1.0/24",
+		},
+		{
+			input:    "0x5",
+			wantErr:  false,
+			expected: "0.0.0.5/32",
+		},
+		{
+			input:    "16777216", // The smallest decimal taken as an address
+			wantErr:  false,
+			expected: "1.0.0.0/32",
+		},
+		{
+			input:   "16777215",
+			wantErr: true,
+		},
+		{
+			input:   "2560", // A mistyped octet, not 0.0.10.0
+			wantErr: true,
+		},
+		{
+			input:   "4294967296", // Beyond 32 bits
+			wantErr: true,
+		},
 		{
 			input:   "invalid",
 			wantErr: true,
@@ -85,3 +136,203 @@ func TestParseInput(t *testing.T) {
 		}
 	}
 }
+
+func TestParseAlgorithms(t *testing.T) {
+	known := []string{"ssh-ed25519", "ssh-rsa", "ssh-dss"}
+
+	got, err := parseAlgorithms("ssh-rsa, ssh-dss,", known)
+	if err != nil {
+		t.Fatalf("parseAlgorithms() error = %v", err)
+	}
+	if want := []string{"ssh-rsa", "ssh-dss"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("parseAlgorithms() = %v, want %v", got, want)
+	}
+
+	if _, err := parseAlgorithms("ssh-rsa,bogus", known); err == nil {
+		t.Errorf("parseAlgorithms() with unknown name expected error")
+	}
+}
+
+func TestExpandOutputPath(t *testing.T) {
+	now := time.Date(2024, 5, 1, 13, 4, 5, 0, time.Local)
+
+	tests := []struct {
+		path, label, want string
+	}{
+		{"found.txt", "10.0.0.0/24", "found.txt"},
+		{"results-{cidr}-{date}.txt", "10.0.0.0/24", "results-10.0.0.0_24-2024-05-01.txt"},
+		{"out/{cidr}_{date}_{time}.txt", "fe80::/64", "out/fe80___64_2024-05-01_130405.txt"},
+		{"{cidr}.txt", rangesLabel([]string{"3", "10.0.0.1"}), "192.168.3.0_24+10.0.0.1_32.txt"},
+	}
+	for _, tt := range tests {
+		if got := expandOutputPath(tt.path, tt.label, now); got != tt.want {
+			t.Errorf("expandOutputPath(%q, %q) = %q, want %q", tt.path, tt.label, got, tt.want)
+		}
+	}
+}
+
+func TestParseWorkers(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+	}{
+		{"100", 100},
+		{"20000", 20000},
+		{"auto", 8 * autoWorkersPerCPU},
+		{"4x", 32},
+		{"0.5x", 4},
+		{"0.01x", 1},
+		{"100000x", maxAutoWorkers},
+		{"infx", maxAutoWorkers},
+	}
+	for _, tt := range tests {
+		got, err := parseWorkers(tt.in, 8)
+		if err != nil || got != tt.want {
+			t.Errorf("parseWorkers(%q) = %d, %v; want %d", tt.in, got, err, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"", "0", "-4", "x", "0x", "-2x", "nanx", "many"} {
+		if _, err := parseWorkers(bad, 8); err == nil {
+			t.Errorf("parseWorkers(%q) expected error", bad)
+		}
+	}
+}
+
+func TestScanLeavesNoGoroutines(t *testing.T) {
+	// scan reports on stdout; keep the test output clean
+	saved := stdout
+	stdout = io.Discard
+	defer func() { stdout = saved }()
+
+	// os/signal starts its dispatch goroutine on first use and keeps it for
+	// the life of the process; get that out of the way before counting
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	signal.Stop(sig)
+
+	before := runtime.NumGoroutine()
+
+	dir := t.TempDir()
+	blocker := filepath.Join(dir, "file")
+	os.WriteFile(blocker, nil, 0o644)
+
+	for _, cfg := range []*Config{
+		{Workers: 4, Port: 22, Timeout: time.Second},
+		{Workers: 4, Port: 22, Timeout: time.Second, StatsFile: filepath.Join(dir, "stats.json")},
+		// Creating the output fails because a path component is a file
+		{Workers: 4, Port: 22, Timeout: time.Second, OutputFile: filepath.Join(blocker, "found.txt")},
+	} {
+		scan(sliceTargets(nil), cfg, 0)
+	}
+
+	// Exited goroutines can take a moment to be accounted for
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("%d goroutines before scan, %d after", before, after)
+	}
+}
+
+func TestCheckClientVersion(t *testing.T) {
+	for _, ok := range []string{defaultClientVersion, "SSH-2.0-PuTTY_Release_0.80", "SSH-2.0-libssh_0.10.6 comment"} {
+		if err := checkClientVersion(ok); err != nil {
+			t.Errorf("checkClientVersion(%q) error = %v", ok, err)
+		}
+	}
+	for _, bad := range []string{"", "OpenSSH_9.6", "SSH-1.99-OpenSSH", "SSH-2.0-", "SSH-2.0-x\r\nSSH-2.0-y", "SSH-2.0-" + strings.Repeat("x", 250)} {
+		if err := checkClientVersion(bad); err == nil {
+			t.Errorf("checkClientVersion(%q) expected error", bad)
+		}
+	}
+}
+
+func TestNewScanID(t *testing.T) {
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	a, b := newScanID(), newScanID()
+	if !uuid.MatchString(a) {
+		t.Errorf("newScanID() = %q, want a version 4 UUID", a)
+	}
+	if a == b {
+		t.Errorf("newScanID() returned %q twice", a)
+	}
+}
+
+func TestScanListResultsOnly(t *testing.T) {
+	addr, _ := startTestServer(t, "toor")
+	host, portStr, _ := net.SplitHostPort(addr)
+	port, _ := strconv.Atoi(portStr)
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := stdout
+	stdout = w
+	defer func() { stdout = saved }()
+
+	cfg := &Config{Workers: 2, Port: port, User: "root", Password: "toor", Timeout: time.Second, ListOnly: true, NoProgress: true}
+	scan(sliceTargets([]Target{{Host: host}, {Host: host, Port: 1}}), cfg, 2)
+	w.Close()
+
+	out, _ := io.ReadAll(r)
+	if string(out) != host+"\n" {
+		t.Errorf("scan printed %q, want only the found host", out)
+	}
+}
+
+func TestScanSilentUnlessFoundReportsFailures(t *testing.T) {
+	stdoutFile, saved := os.Stdout, stdout
+	defer func() { os.Stdout, stdout = stdoutFile, saved }()
+
+	for _, statsFile := range []string{"stats.json", filepath.Join("missing", "stats.json")} {
+		out, err := os.Create(filepath.Join(t.TempDir(), "stdout"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		os.Stdout = out
+
+		cfg := &Config{Workers: 1, Port: 1, User: "root", Timeout: time.Second, SilentUnlessFound: true, NoProgress: true,
+			StatsFile: filepath.Join(t.TempDir(), statsFile)}
+		if err := holdOutput(cfg); err != nil {
+			t.Fatal(err)
+		}
+		scan(sliceTargets([]Target{{Host: "127.0.0.1"}}), cfg, 1)
+		stdout = saved
+		out.Close()
+
+		data, _ := os.ReadFile(out.Name())
+		failed := strings.Contains(statsFile, "missing")
+		if got := strings.Contains(string(data), "Failed to write stats file"); got != failed || !failed && len(data) > 0 {
+			t.Errorf("-stats-file %s: scan printed %q", statsFile, data)
+		}
+	}
+}
+
+func TestStallWatch(t *testing.T) {
+	start := time.Now()
+	w := stallWatch{limit: time.Minute, since: start}
+	at := func(d time.Duration) time.Time { return start.Add(d) }
+
+	steps := []struct {
+		at    time.Duration
+		stats Stats
+		want  bool
+	}{
+		{10 * time.Second, Stats{Processed: 5, Active: 4}, false},
+		{60 * time.Second, Stats{Processed: 5, Active: 4}, false}, // idle for 50s
+		{70 * time.Second, Stats{Processed: 5, Active: 4}, true},
+		{90 * time.Second, Stats{Processed: 5, Active: 4}, false}, // already reported
+		{100 * time.Second, Stats{Processed: 6, Active: 4}, false},
+		{200 * time.Second, Stats{Processed: 6, Active: 0}, false}, // paused, nothing in flight
+		{250 * time.Second, Stats{Processed: 6, Active: 4}, false},
+		{261 * time.Second, Stats{Processed: 6, Active: 4}, true},
+	}
+	for _, step := range steps {
+		if got := w.update(at(step.at), step.stats); got != step.want {
+			t.Errorf("update(+%v, %+v) = %v, want %v", step.at, step.stats, got, step.want)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"iter"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// mangleRules selects the -mangle rules applied to each base password.
+type mangleRules uint8
+
+const (
+	mangleCase  mangleRules = 1 << iota // Password, PASSWORD
+	mangleYears                         // password2026, password2025, ...
+	mangleLeet                          // p@$$w0rd
+)
+
+// Number of years, counting back from the current one, appended by -mangle years
+const mangleYearSpan = 4
+
+var mangleNames = map[string]mangleRules{
+	"case":  mangleCase,
+	"years": mangleYears,
+	"leet":  mangleLeet,
+	"all":   mangleCase | mangleYears | mangleLeet,
+}
+
+var leetReplacer = strings.NewReplacer("a", "@", "e", "3", "i", "1", "o", "0", "s", "$")
+
+// parseMangle parses a comma-separated -mangle list such as "case,years".
+func parseMangle(list string) (mangleRules, error) {
+	var rules mangleRules
+	for _, name := range strings.Split(list, ",") {
+		rule, ok := mangleNames[strings.TrimSpace(name)]
+		if !ok {
+			return 0, fmt.Errorf("unknown rule %q: use case, years, leet or all", name)
+		}
+		rules |= rule
+	}
+	return rules, nil
+}
+
+// candidates yields each base password followed by its variants under the
+// rules, skipping repeats. Years count back from year. Variants are made as
+// they are consumed, so only the set of passwords already yielded is kept.
+func (rules mangleRules) candidates(bases []string, year int) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		seen := make(map[string]bool)
+		emit := func(password string) bool {
+			if seen[password] {
+				return true
+			}
+			seen[password] = true
+			return yield(password)
+		}
+		for _, base := range bases {
+			for password := range rules.variants(base, year) {
+				if !emit(password) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// variants yields base and its mutations, roughly from the most to the least
+// commonly seen: all spellings first, then each of them with a year.
+func (rules mangleRules) variants(base string, year int) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		forms := []string{base}
+		if rules&mangleCase != 0 {
+			forms = append(forms, capitalize(base), strings.ToUpper(base))
+		}
+		if rules&mangleLeet != 0 {
+			// Ranges over the spellings so far, not the ones appended
+			for _, form := range forms {
+				forms = append(forms, leetReplacer.Replace(form))
+			}
+		}
+
+		suffixes := []string{""}
+		if rules&mangleYears != 0 {
+			for y := year; y > year-mangleYearSpan; y-- {
+				suffixes = append(suffixes, strconv.Itoa(y))
+			}
+		}
+
+		for _, suffix := range suffixes {
+			for _, form := range forms {
+				if !yield(form + suffix) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func capitalize(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError {
+		return s
+	}
+	return string(unicode.ToUpper(r)) + s[size:]
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net"
+	"reflect"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestParseMangle(t *testing.T) {
+	if got, err := parseMangle("case, years"); err != nil || got != mangleCase|mangleYears {
+		t.Errorf("parseMangle(case, years) = %v, %v", got, err)
+	}
+	if got, _ := parseMangle("all"); got != mangleCase|mangleYears|mangleLeet {
+		t.Errorf("parseMangle(all) = %v", got)
+	}
+	for _, bad := range []string{"", "leet,", "rot13"} {
+		if _, err := parseMangle(bad); err == nil {
+			t.Errorf("parseMangle(%q) expected error", bad)
+		}
+	}
+}
+
+func TestMangleCandidates(t *testing.T) {
+	tests := []struct {
+		rules mangleRules
+		bases []string
+		want  []string
+	}{
+		{0, []string{"admin", "root", "admin"}, []string{"admin", "root"}},
+		{mangleCase, []string{"admin"}, []string{"admin", "Admin", "ADMIN"}},
+		{mangleLeet, []string{"secret"}, []string{"secret", "$3cr3t"}},
+		{mangleYears, []string{"spring"}, []string{"spring", "spring2026", "spring2025", "spring2024", "spring2023"}},
+		{mangleCase | mangleYears, []string{"x"}, []string{
+			"x", "X",
+			"x2026", "X2026", "x2025", "X2025", "x2024", "X2024", "x2023", "X2023",
+		}},
+	}
+	for _, tt := range tests {
+		if got := slices.Collect(tt.rules.candidates(tt.bases, 2026)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%v.candidates(%q) = %q, want %q", tt.rules, tt.bases, got, tt.want)
+		}
+	}
+
+	// Stopping early must not generate the rest
+	all := mangleCase | mangleYears | mangleLeet
+	var n int
+	for range all.candidates([]string{"password", "letmein"}, 2026) {
+		if n++; n == 3 {
+			break
+		}
+	}
+	if n != 3 {
+		t.Errorf("consumed %d candidates, want 3", n)
+	}
+}
+
+func TestScannerMangle(t *testing.T) {
+	addr, _ := startTestServer(t, "T00r")
+	host, portStr, _ := net.SplitHostPort(addr)
+	port, _ := strconv.Atoi(portStr)
+
+	cfg := &Config{Workers: 1, Port: port, User: "root", Password: "toor", Timeout: time.Second, Mangle: mangleCase | mangleLeet}
+	got := runScanner(NewScanner(cfg), host)
+	if len(got) != 1 || !got[0].Success || got[0].Password != "T00r" {
+		t.Fatalf("Run() = %+v, want success with T00r", got)
+	}
+}
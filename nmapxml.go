@@ -0,0 +1,196 @@
+package main
+
+import (
+	"cmp"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// The subset of Nmap's XML output (nmap.dtd) written by -fmt nmap-xml: one
+// host per address with an open ssh port for every port that completed an
+// SSH handshake, the host key as an ssh-hostkey script, and valid
+// credentials or -probe-auth-methods findings as the matching Nmap scripts.
+type nmapRun struct {
+	XMLName          xml.Name     `xml:"nmaprun"`
+	Scanner          string       `xml:"scanner,attr"`
+	Args             string       `xml:"args,attr"`
+	Start            int64        `xml:"start,attr"`
+	StartStr         string       `xml:"startstr,attr"`
+	Version          string       `xml:"version,attr"`
+	XMLOutputVersion string       `xml:"xmloutputversion,attr"`
+	Hosts            []*nmapHost  `xml:"host"`
+	RunStats         nmapRunStats `xml:"runstats"`
+
+	started time.Time
+	byAddr  map[string]*nmapHost
+}
+
+type nmapHost struct {
+	Status    nmapStatus     `xml:"status"`
+	Addresses []nmapAddress  `xml:"address"`
+	Hostnames *nmapHostnames `xml:"hostnames"`
+	Ports     []nmapPort     `xml:"ports>port"`
+}
+
+type nmapStatus struct {
+	State  string `xml:"state,attr"`
+	Reason string `xml:"reason,attr"`
+}
+
+type nmapAddress struct {
+	Addr     string `xml:"addr,attr"`
+	AddrType string `xml:"addrtype,attr"`
+}
+
+type nmapHostnames struct {
+	Hostnames []nmapHostname `xml:"hostname"`
+}
+
+type nmapHostname struct {
+	Name string `xml:"name,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type nmapPort struct {
+	Protocol string       `xml:"protocol,attr"`
+	PortID   int          `xml:"portid,attr"`
+	State    nmapStatus   `xml:"state"`
+	Service  nmapService  `xml:"service"`
+	Scripts  []nmapScript `xml:"script"`
+}
+
+type nmapService struct {
+	Name   string `xml:"name,attr"`
+	Method string `xml:"method,attr"`
+	Conf   int    `xml:"conf,attr"`
+}
+
+type nmapScript struct {
+	ID     string `xml:"id,attr"`
+	Output string `xml:"output,attr"`
+}
+
+type nmapRunStats struct {
+	Finished nmapFinished  `xml:"finished"`
+	Hosts    nmapHostStats `xml:"hosts"`
+}
+
+type nmapFinished struct {
+	Time    int64   `xml:"time,attr"`
+	TimeStr string  `xml:"timestr,attr"`
+	Elapsed float64 `xml:"elapsed,attr"`
+	Exit    string  `xml:"exit,attr"`
+}
+
+type nmapHostStats struct {
+	Up    int `xml:"up,attr"`
+	Down  int `xml:"down,attr"`
+	Total int `xml:"total,attr"`
+}
+
+func newNmapRun(start time.Time) *nmapRun {
+	return &nmapRun{
+		Scanner:          "ssh-scanner",
+		Args:             strings.Join(os.Args, " "),
+		Start:            start.Unix(),
+		StartStr:         start.Format(time.ANSIC),
+		Version:          "1.0",
+		XMLOutputVersion: "1.05",
+		started:          start,
+		byAddr:           make(map[string]*nmapHost),
+	}
+}
+
+// add records res if its port spoke SSH. Other results don't tell us
+// anything Nmap's open/closed model could express reliably.
+func (run *nmapRun) add(res Result) {
+	if res.Fingerprint == "" && !res.Success {
+		return
+	}
+
+	host := run.byAddr[res.IP]
+	if host == nil {
+		host = &nmapHost{Status: nmapStatus{State: "up", Reason: "syn-ack"}}
+		host.Addresses, host.Hostnames = nmapAddresses(res.IP)
+		run.byAddr[res.IP] = host
+		run.Hosts = append(run.Hosts, host)
+	}
+	if res.Hostname != "" && host.Hostnames == nil {
+		host.Hostnames = &nmapHostnames{Hostnames: []nmapHostname{{Name: res.Hostname, Type: "PTR"}}}
+	}
+
+	port := nmapPort{
+		Protocol: "tcp",
+		PortID:   res.Port,
+		State:    nmapStatus{State: "open", Reason: "syn-ack"},
+		Service:  nmapService{Name: "ssh", Method: "probed", Conf: 10},
+	}
+	if res.Fingerprint != "" {
+		port.Scripts = append(port.Scripts, nmapScript{ID: "ssh-hostkey", Output: res.Fingerprint})
+	}
+	switch {
+	case len(res.AuthMethods) > 0:
+		port.Scripts = append(port.Scripts, nmapScript{
+			ID:     "ssh-auth-methods",
+			Output: "\n  Supported authentication methods: \n    " + strings.Join(res.AuthMethods, "\n    ") + "\n",
+		})
+	case res.Success:
+		password := cmp.Or(res.Password, res.target.Password)
+		port.Scripts = append(port.Scripts, nmapScript{
+			ID:     "ssh-brute",
+			Output: fmt.Sprintf("\n  Accounts:\n    %s:%s - Valid credentials\n", res.User, password),
+		})
+	}
+	host.Ports = append(host.Ports, port)
+}
+
+// nmapAddresses describes a target host. Hostnames from -iL are resolved,
+// since Nmap consumers key hosts on the address.
+func nmapAddresses(host string) ([]nmapAddress, *nmapHostnames) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []nmapAddress{ipAddress(ip)}, nil
+	}
+
+	names := &nmapHostnames{Hostnames: []nmapHostname{{Name: host, Type: "user"}}}
+	ips, err := net.LookupIP(host)
+	if err != nil || len(ips) == 0 {
+		return nil, names
+	}
+	return []nmapAddress{ipAddress(ips[0])}, names
+}
+
+func ipAddress(ip net.IP) nmapAddress {
+	if ip.To4() != nil {
+		return nmapAddress{Addr: ip.String(), AddrType: "ipv4"}
+	}
+	return nmapAddress{Addr: ip.String(), AddrType: "ipv6"}
+}
+
+// writeTo finishes the run statistics and writes the document.
+func (run *nmapRun) writeTo(w io.Writer, finished time.Time) error {
+	run.RunStats = nmapRunStats{
+		Finished: nmapFinished{
+			Time:    finished.Unix(),
+			TimeStr: finished.Format(time.ANSIC),
+			Elapsed: finished.Sub(run.started).Seconds(),
+			Exit:    "success",
+		},
+		Hosts: nmapHostStats{Up: len(run.Hosts), Total: len(run.Hosts)},
+	}
+
+	if _, err := io.WriteString(w, xml.Header+"<!DOCTYPE nmaprun>\n"); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(run); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResultFileNmapXML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scan.xml")
+	rf, err := createResultFile(path, "nmap-xml", &Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := Result{IP: "10.0.0.1", Port: 22, User: "root", Success: true, Fingerprint: "SHA256:aaa",
+		Hostname: "web01.example.com", target: Target{Password: "toor"}}
+	for _, res := range []Result{
+		found,
+		{IP: "10.0.0.1", Port: 2222, User: "root", Fingerprint: "SHA256:bbb", Category: failAuth},
+		{IP: "10.0.0.2", Port: 22, Category: failTimeout},
+		{IP: "fd00::3", Port: 22, User: "root", Success: true, AuthMethods: []string{"publickey", "password"}},
+	} {
+		rf.addResult(res)
+		if res.Success {
+			rf.add(foundLabel(res, &Config{Port: 22}))
+		}
+	}
+	if err := rf.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "<?xml") {
+		t.Fatalf("output does not start with an XML declaration:\n%s", data)
+	}
+
+	// Decode with a schema of its own so the test checks the document, not
+	// just that the writer's structs round-trip
+	var doc struct {
+		XMLName xml.Name `xml:"nmaprun"`
+		Scanner string   `xml:"scanner,attr"`
+		Hosts   []struct {
+			Status struct {
+				State string `xml:"state,attr"`
+			} `xml:"status"`
+			Address struct {
+				Addr     string `xml:"addr,attr"`
+				AddrType string `xml:"addrtype,attr"`
+			} `xml:"address"`
+			Hostnames []struct {
+				Name string `xml:"name,attr"`
+				Type string `xml:"type,attr"`
+			} `xml:"hostnames>hostname"`
+			Ports []struct {
+				PortID int `xml:"portid,attr"`
+				State  struct {
+					State string `xml:"state,attr"`
+				} `xml:"state"`
+				Service struct {
+					Name string `xml:"name,attr"`
+				} `xml:"service"`
+				Scripts []struct {
+					ID     string `xml:"id,attr"`
+					Output string `xml:"output,attr"`
+				} `xml:"script"`
+			} `xml:"ports>port"`
+		} `xml:"host"`
+		RunStats struct {
+			Hosts struct {
+				Up int `xml:"up,attr"`
+			} `xml:"hosts"`
+		} `xml:"runstats"`
+	}
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("output is not valid XML: %v\n%s", err, data)
+	}
+
+	if doc.Scanner != "ssh-scanner" || len(doc.Hosts) != 2 || doc.RunStats.Hosts.Up != 2 {
+		t.Fatalf("got scanner %q with %d hosts (%d up), want 2 hosts:\n%s", doc.Scanner, len(doc.Hosts), doc.RunStats.Hosts.Up, data)
+	}
+
+	h := doc.Hosts[0]
+	if h.Address.Addr != "10.0.0.1" || h.Address.AddrType != "ipv4" || h.Status.State != "up" || len(h.Ports) != 2 {
+		t.Fatalf("first host = %+v", h)
+	}
+	if len(h.Hostnames) != 1 || h.Hostnames[0].Name != "web01.example.com" || h.Hostnames[0].Type != "PTR" {
+		t.Errorf("first host names = %+v, want the PTR name", h.Hostnames)
+	}
+	p := h.Ports[0]
+	if p.PortID != 22 || p.State.State != "open" || p.Service.Name != "ssh" || len(p.Scripts) != 2 ||
+		p.Scripts[1].ID != "ssh-brute" || !strings.Contains(p.Scripts[1].Output, "root:toor - Valid credentials") {
+		t.Errorf("port 22 = %+v, want open ssh with host key and valid credentials", p)
+	}
+	if p := h.Ports[1]; p.PortID != 2222 || len(p.Scripts) != 1 || p.Scripts[0].ID != "ssh-hostkey" {
+		t.Errorf("port 2222 = %+v, want open ssh with only a host key", p)
+	}
+
+	h = doc.Hosts[1]
+	if h.Address.AddrType != "ipv6" || len(h.Ports) != 1 || h.Ports[0].Scripts[0].ID != "ssh-auth-methods" {
+		t.Errorf("second host = %+v, want ipv6 with auth methods", h)
+	}
+}
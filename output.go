@@ -0,0 +1,475 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// resultFile is one output of a scan: the -o file in its -fmt, the -json
+// log or the -reachable-file. With -sign every byte written is also fed to a
+// running digest that is stored next to the file on Close.
+type resultFile struct {
+	path   string
+	format string
+	f      *os.File
+	w      io.Writer
+	cfg    *Config
+	err    error // The first failed write, reported by Close
+
+	// Collects the document for -fmt nmap-xml, written out on Close
+	nmap *nmapRun
+
+	// -sign state
+	algorithm string
+	digest    hash.Hash
+	lines     int
+	started   time.Time
+}
+
+// sortResults orders results by address, then port. Hosts given by name
+// come after all addresses, in alphabetical order.
+func sortResults(results []Result) {
+	slices.SortStableFunc(results, func(a, b Result) int {
+		ipA, errA := netip.ParseAddr(a.IP)
+		ipB, errB := netip.ParseAddr(b.IP)
+		switch {
+		case errA == nil && errB == nil:
+			if c := ipA.Compare(ipB); c != 0 {
+				return c
+			}
+		case errA == nil:
+			return -1
+		case errB == nil:
+			return 1
+		default:
+			if c := strings.Compare(a.IP, b.IP); c != 0 {
+				return c
+			}
+		}
+		return cmp.Compare(a.Port, b.Port)
+	})
+}
+
+// resultFiles fans the results out to every output of a scan.
+type resultFiles []*resultFile
+
+// createResultFiles opens the -o, -json and -reachable-file outputs,
+// whichever are set. It returns nil when there are none.
+func createResultFiles(cfg *Config) (resultFiles, error) {
+	var files resultFiles
+	for _, out := range []struct{ path, format string }{
+		{cfg.OutputFile, cfg.Format},
+		{cfg.JSONFile, "jsonl"},
+		{cfg.ReachableFile, "reachable"},
+	} {
+		if out.path == "" {
+			continue
+		}
+		rf, err := createResultFile(out.path, out.format, cfg)
+		if err != nil {
+			files.Close()
+			return nil, err
+		}
+		files = append(files, rf)
+	}
+	return files, nil
+}
+
+// add and addResult write to every output. A failure is kept for Close to
+// report, so a full disk doesn't interrupt the scan.
+func (files resultFiles) add(line string) {
+	for _, rf := range files {
+		rf.keep(rf.add(line))
+	}
+}
+
+func (files resultFiles) addResult(res Result) {
+	for _, rf := range files {
+		rf.keep(rf.addResult(res))
+	}
+}
+
+// Close closes every output, reporting the failures of all of them.
+func (files resultFiles) Close() error {
+	var errs []error
+	for _, rf := range files {
+		if err := rf.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", rf.path, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// createResultFile creates the output at path in the given format: text,
+// nmap-xml, jsonl or reachable.
+func createResultFile(path, format string, cfg *Config) (*resultFile, error) {
+	// Nested paths such as results/{date}/{cidr}.txt shouldn't need a mkdir first
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if cfg.FoundOnce && format == "text" {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	f, err := os.OpenFile(path, flags, 0o666)
+	if err != nil {
+		return nil, err
+	}
+
+	rf := &resultFile{path: path, format: format, f: f, w: f, cfg: cfg}
+	if format == "nmap-xml" {
+		rf.nmap = newNmapRun(time.Now())
+	}
+	if cfg.Sign {
+		rf.algorithm, rf.digest = "sha256", sha256.New()
+		if cfg.SignKey != "" {
+			rf.algorithm, rf.digest = "hmac-sha256", hmac.New(sha256.New, []byte(cfg.SignKey))
+		}
+		rf.w = io.MultiWriter(f, rf.digest)
+		rf.started = time.Now()
+	}
+	return rf, nil
+}
+
+// add appends one found host to a text output.
+func (rf *resultFile) add(line string) error {
+	switch rf.format {
+	case "nmap-xml", "jsonl", "reachable":
+		return nil
+	}
+	rf.lines++
+	_, err := io.WriteString(rf.w, line+"\n")
+	return err
+}
+
+// addResult passes every scan result, found or not, to the structured
+// formats. The jsonl log gets one Result object per line, and the reachable
+// list the targets whose SSH server rejected the credentials, in the -iL
+// format for a follow-up scan.
+func (rf *resultFile) addResult(res Result) error {
+	switch rf.format {
+	case "reachable":
+		if res.Category != failAuth {
+			return nil
+		}
+		rf.lines++
+		_, err := io.WriteString(rf.w, foundLabel(res, rf.cfg)+"\n")
+		return err
+	case "nmap-xml":
+		rf.nmap.add(res)
+	case "jsonl":
+		line, err := json.Marshal(res)
+		if err != nil {
+			return err
+		}
+		rf.lines++
+		_, err = rf.w.Write(append(line, '\n'))
+		return err
+	}
+	return nil
+}
+
+// keep records err if it is the first write to fail.
+func (rf *resultFile) keep(err error) {
+	if rf.err == nil {
+		rf.err = err
+	}
+}
+
+// Close closes the output and, with -sign, writes the signature file. It
+// reports a write that failed earlier too, and then leaves the file
+// unsigned, as it is incomplete.
+func (rf *resultFile) Close() error {
+	if rf.nmap != nil {
+		counter := &lineCounter{}
+		if err := rf.nmap.writeTo(io.MultiWriter(rf.w, counter), time.Now()); err != nil {
+			rf.f.Close()
+			return errors.Join(rf.err, err)
+		}
+		rf.lines = counter.lines
+	}
+	if err := rf.f.Close(); err != nil {
+		return errors.Join(rf.err, err)
+	}
+	if rf.err != nil || rf.digest == nil {
+		return rf.err
+	}
+	return os.WriteFile(rf.path+".sig", rf.signature(time.Now()), 0o644)
+}
+
+// lineCounter counts the lines written through it.
+type lineCounter struct {
+	lines int
+}
+
+func (c *lineCounter) Write(p []byte) (int, error) {
+	c.lines += bytes.Count(p, []byte{'\n'})
+	return len(p), nil
+}
+
+// signature renders the .sig file: one "key: value" per line, with the
+// digest covering exactly the bytes of the output file.
+func (rf *resultFile) signature(finished time.Time) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "algorithm: %s\n", rf.algorithm)
+	fmt.Fprintf(&b, "lines: %d\n", rf.lines)
+	fmt.Fprintf(&b, "started: %s\n", rf.started.Format(time.RFC3339))
+	fmt.Fprintf(&b, "finished: %s\n", finished.Format(time.RFC3339))
+	fmt.Fprintf(&b, "digest: %s\n", hex.EncodeToString(rf.digest.Sum(nil)))
+	return b.Bytes()
+}
+
+// statsSnapshot is the content of -stats-file.
+type statsSnapshot struct {
+	ScanID string `json:"scan_id"`
+	Tag    string `json:"tag,omitempty"`
+	Stats
+	Total     uint64    `json:"total"`
+	Rate      float64   `json:"rate"`
+	Elapsed   float64   `json:"elapsed_seconds"`
+	StartedAt time.Time `json:"started_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Done      bool      `json:"done"`
+}
+
+func newStatsSnapshot(cfg *Config, stats Stats, total uint64, start time.Time, done bool) statsSnapshot {
+	now := time.Now()
+	elapsed := now.Sub(start).Seconds()
+	return statsSnapshot{
+		ScanID:    cfg.ScanID,
+		Tag:       cfg.Tag,
+		Stats:     stats,
+		Total:     total,
+		Rate:      float64(stats.Processed) / max(elapsed, 0.001),
+		Elapsed:   elapsed,
+		StartedAt: start,
+		UpdatedAt: now,
+		Done:      done,
+	}
+}
+
+// writeStatsFile replaces path with v as JSON. The data goes to a temporary
+// file in the same directory first and is renamed into place, so a reader
+// never sees a partial file.
+func writeStatsFile(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	// CreateTemp uses 0600, but dashboards often run as another user
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// rateLog writes the -rate-log CSV: one row per rateLogInterval with the
+// attempts finished in that interval, for plotting how throughput varied.
+type rateLog struct {
+	f     *os.File
+	w     *csv.Writer
+	start time.Time
+	last  time.Time
+	prev  int32 // Processed at the last row
+}
+
+func createRateLog(path string, start time.Time) (*rateLog, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	l := &rateLog{f: f, w: csv.NewWriter(f), start: start, last: start}
+	l.w.Write([]string{"elapsed_s", "processed", "interval_processed", "rate_per_s", "found", "active"})
+	return l, nil
+}
+
+// add writes a row for the interval ending at now.
+func (l *rateLog) add(now time.Time, stats Stats) {
+	interval := now.Sub(l.last).Seconds()
+	n := stats.Processed - l.prev
+	rate := 0.0
+	if interval > 0 {
+		rate = float64(n) / interval
+	}
+	l.w.Write([]string{
+		strconv.FormatFloat(now.Sub(l.start).Seconds(), 'f', 1, 64),
+		strconv.Itoa(int(stats.Processed)),
+		strconv.Itoa(int(n)),
+		strconv.FormatFloat(rate, 'f', 2, 64),
+		strconv.Itoa(int(stats.Found)),
+		strconv.Itoa(int(stats.Active)),
+	})
+	l.last, l.prev = now, stats.Processed
+}
+
+// Close flushes the rows and reports the first write error, if any.
+func (l *rateLog) Close() error {
+	l.w.Flush()
+	return errors.Join(l.w.Error(), l.f.Close())
+}
+
+// progressFeed writes the -progress-fd stream: a statsSnapshot per line,
+// for frontends that render progress themselves. A write error, usually the
+// reader having gone away, ends the feed without failing the scan.
+type progressFeed struct {
+	f   *os.File
+	enc *json.Encoder
+	err error
+}
+
+// openProgressFeed opens dest, an inherited file descriptor number such as 3
+// or the path of a file or named pipe. Stdin, stdout and stderr (0 to 2) are
+// refused, as closing the feed would close them.
+func openProgressFeed(dest string) (*progressFeed, error) {
+	var f *os.File
+	if fd, err := strconv.Atoi(dest); err == nil {
+		if fd < 3 {
+			return nil, fmt.Errorf("invalid file descriptor %d: use 3 or above", fd)
+		}
+		f = os.NewFile(uintptr(fd), "progress-fd")
+		if _, err := f.Stat(); err != nil {
+			return nil, fmt.Errorf("file descriptor %d is not open", fd)
+		}
+	} else if f, err = os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644); err != nil {
+		return nil, err
+	}
+	return &progressFeed{f: f, enc: json.NewEncoder(f)}, nil
+}
+
+func (p *progressFeed) add(snapshot statsSnapshot) {
+	if p.err == nil {
+		p.err = p.enc.Encode(snapshot)
+	}
+}
+
+func (p *progressFeed) Close() error {
+	return errors.Join(p.err, p.f.Close())
+}
+
+// loadFound reads the hosts an earlier run wrote to the text output at path,
+// keyed like foundLabel, for -found-once. A missing file lists none.
+func loadFound(path string) (map[string]bool, error) {
+	known := make(map[string]bool)
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return known, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		// Lines may carry the accepted credentials after the host
+		if fields := strings.Fields(sc.Text()); len(fields) > 0 {
+			known[fields[0]] = true
+		}
+	}
+	return known, sc.Err()
+}
+
+// stdout is where a scan's console output goes: os.Stdout, or the
+// heldOutput of -silent-unless-found. It is set before the scan starts and
+// not changed while one runs, so printing needs no more locking than
+// os.Stdout would.
+var stdout io.Writer = os.Stdout
+
+// heldOutput keeps stdout back for -silent-unless-found: everything printed
+// goes to an unlinked temporary file until release writes it out, or
+// discard drops it; from then on output goes straight through. Both are
+// no-ops on a nil heldOutput and once either has run. It is safe for
+// concurrent use, so the progress updater can print while the scan loop
+// releases it.
+type heldOutput struct {
+	mu     sync.Mutex
+	stdout io.Writer
+	buf    *os.File // nil once released or discarded
+}
+
+// holdOutput starts holding stdout back when cfg asks for it, or reports
+// why it can't.
+func holdOutput(cfg *Config) error {
+	if !cfg.SilentUnlessFound {
+		return nil
+	}
+	buf, err := os.CreateTemp("", "ssh-scanner-*")
+	if err != nil {
+		return err
+	}
+	os.Remove(buf.Name())
+	cfg.held = &heldOutput{stdout: os.Stdout, buf: buf}
+	stdout = cfg.held
+	return nil
+}
+
+func (h *heldOutput) Write(p []byte) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.buf != nil {
+		return h.buf.Write(p)
+	}
+	return h.stdout.Write(p)
+}
+
+func (h *heldOutput) release() {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.buf == nil {
+		return
+	}
+	h.buf.Seek(0, io.SeekStart)
+	io.Copy(h.stdout, h.buf)
+	h.buf.Close()
+	h.buf = nil
+}
+
+func (h *heldOutput) discard() {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.buf == nil {
+		return
+	}
+	h.buf.Close()
+	h.buf = nil
+}
@@ -0,0 +1,353 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestResultFileSign(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "found.txt")
+	cfg := &Config{OutputFile: path, Sign: true, SignKey: "secret"}
+
+	rf, err := createResultFile(path, "text", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rf.add("10.0.0.1")
+	rf.add("10.0.0.2:2222")
+	if err := rf.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(data)
+	want := hex.EncodeToString(mac.Sum(nil))
+
+	sig, err := os.ReadFile(path + ".sig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(string(sig)), "\n") {
+		key, value, _ := strings.Cut(line, ": ")
+		fields[key] = value
+	}
+	if fields["algorithm"] != "hmac-sha256" || fields["lines"] != "2" || fields["digest"] != want {
+		t.Errorf("signature = %v, want hmac-sha256 over 2 lines with digest %s", fields, want)
+	}
+}
+
+func TestResultFileUnsigned(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "found.txt")
+	rf, err := createResultFile(path, "text", &Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rf.add("10.0.0.1")
+	if err := rf.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(path + ".sig"); !os.IsNotExist(err) {
+		t.Errorf("signature written without -sign: %v", err)
+	}
+}
+
+func TestResultFileCreatesParentDirs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results", "2024-05-01", "found.txt")
+	rf, err := createResultFile(path, "text", &Config{})
+	if err != nil {
+		t.Fatalf("createResultFile() error = %v", err)
+	}
+	if err := rf.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("output file not created: %v", err)
+	}
+}
+
+func TestResultFilesJSONL(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &Config{OutputFile: filepath.Join(dir, "hosts.txt"), Format: "text", JSONFile: filepath.Join(dir, "out.jsonl")}
+	files, err := createResultFiles(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := Result{IP: "10.0.0.1", Port: 22, User: "root", Success: true}
+	for _, res := range []Result{found, {IP: "10.0.0.2", Port: 22, Category: failTimeout}} {
+		files.addResult(res)
+		if res.Success {
+			files.add(res.IP)
+		}
+	}
+	if err := files.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, _ := os.ReadFile(cfg.OutputFile); string(data) != "10.0.0.1\n" {
+		t.Errorf("-o file = %q, want only the found host", data)
+	}
+	data, err := os.ReadFile(cfg.JSONFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ips []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var res struct{ IP string }
+		if err := json.Unmarshal([]byte(line), &res); err != nil {
+			t.Fatalf("line %q: %v", line, err)
+		}
+		ips = append(ips, res.IP)
+	}
+	if strings.Join(ips, ",") != "10.0.0.1,10.0.0.2" {
+		t.Errorf("-json file has results for %v, want every result", ips)
+	}
+}
+
+func TestResultFilesReachable(t *testing.T) {
+	cfg := &Config{Port: 22, ReachableFile: filepath.Join(t.TempDir(), "reachable.txt")}
+	files, err := createResultFiles(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, res := range []Result{
+		{IP: "10.0.0.1", Port: 22, Success: true},
+		{IP: "10.0.0.2", Port: 22, Category: failAuth},
+		{IP: "10.0.0.3", Port: 22, Category: failTimeout},
+		{IP: "10.0.0.4", Port: 2222, Category: failAuth},
+	} {
+		files.addResult(res)
+		if res.Success {
+			files.add(res.IP)
+		}
+	}
+	if err := files.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, _ := os.ReadFile(cfg.ReachableFile); string(data) != "10.0.0.2\n10.0.0.4:2222\n" {
+		t.Errorf("-reachable-file = %q, want only the auth failures", data)
+	}
+}
+
+func TestFoundOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts.txt")
+	if known, err := loadFound(path); err != nil || len(known) != 0 {
+		t.Fatalf("loadFound() of a missing file = %v, %v; want no hosts", known, err)
+	}
+	if err := os.WriteFile(path, []byte("10.0.0.1\n10.0.0.2:2222 admin secret\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	known, err := loadFound(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !known["10.0.0.1"] || !known["10.0.0.2:2222"] || len(known) != 2 {
+		t.Errorf("loadFound() = %v, want both hosts", known)
+	}
+
+	cfg := &Config{OutputFile: path, Format: "text", FoundOnce: true}
+	files, err := createResultFiles(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files.add("10.0.0.3")
+	if err := files.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "10.0.0.1\n10.0.0.2:2222 admin secret\n10.0.0.3\n" {
+		t.Errorf("-o file = %q, want the new host appended", data)
+	}
+}
+
+func TestResultFilesWriteError(t *testing.T) {
+	if _, err := os.Stat("/dev/full"); err != nil {
+		t.Skip("no /dev/full to fill up")
+	}
+	files, err := createResultFiles(&Config{OutputFile: "/dev/full", Format: "text"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	files.add("10.0.0.1")
+	if err := files.Close(); err == nil || !strings.Contains(err.Error(), "/dev/full") {
+		t.Errorf("Close() after a failed write = %v, want the write error", err)
+	}
+}
+
+func TestSortResults(t *testing.T) {
+	results := []Result{
+		{IP: "10.0.0.10", Port: 22},
+		{IP: "web01"},
+		{IP: "10.0.0.9", Port: 2222},
+		{IP: "fd00::1", Port: 22},
+		{IP: "10.0.0.9", Port: 22},
+		{IP: "db01"},
+	}
+	sortResults(results)
+
+	var got []string
+	for _, res := range results {
+		got = append(got, net.JoinHostPort(res.IP, strconv.Itoa(res.Port)))
+	}
+	want := "10.0.0.9:22 10.0.0.9:2222 10.0.0.10:22 [fd00::1]:22 db01:0 web01:0"
+	if strings.Join(got, " ") != want {
+		t.Errorf("sortResults() = %v, want %s", got, want)
+	}
+}
+
+func TestRateLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rate.csv")
+	start := time.Now()
+	l, err := createRateLog(path, start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.add(start.Add(time.Second), Stats{Processed: 100, Found: 1, Active: 50})
+	l.add(start.Add(3*time.Second), Stats{Processed: 150, Found: 2, Active: 50})
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "elapsed_s,processed,interval_processed,rate_per_s,found,active\n" +
+		"1.0,100,100,100.00,1,50\n" +
+		"3.0,150,50,25.00,2,50\n"
+	if string(data) != want {
+		t.Errorf("rate log =\n%s\nwant\n%s", data, want)
+	}
+}
+
+func TestWriteStatsFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "stats.json")
+	start := time.Now().Add(-10 * time.Second)
+
+	for _, processed := range []int32{10, 20} {
+		snap := newStatsSnapshot(&Config{ScanID: "id"}, Stats{Processed: processed, Found: 1}, 100, start, processed == 20)
+		if err := writeStatsFile(path, snap); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got statsSnapshot
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("stats file is not valid JSON: %v\n%s", err, data)
+	}
+	if got.ScanID != "id" || got.Processed != 20 || got.Total != 100 || !got.Done || got.Rate < 1.5 || got.Rate > 2.5 {
+		t.Errorf("stats file = %+v, want the second snapshot at ~2/s", got)
+	}
+
+	// Only the final file is left behind, no temporaries
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("directory holds %d entries, want just the stats file", len(entries))
+	}
+}
+
+func TestProgressFeed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "progress.jsonl")
+	feed, err := openProgressFeed(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed.add(statsSnapshot{Stats: Stats{Processed: 10}, Total: 256})
+	feed.add(statsSnapshot{Stats: Stats{Processed: 256, Found: 3}, Total: 256, Done: true})
+	if err := feed.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(path)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("feed has %d lines, want 2: %q", len(lines), data)
+	}
+	var last struct {
+		Processed, Found int32
+		Total            uint64
+		Done             bool
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &last); err != nil {
+		t.Fatal(err)
+	}
+	if last.Processed != 256 || last.Found != 3 || last.Total != 256 || !last.Done {
+		t.Errorf("last line = %+v", last)
+	}
+
+	if _, err := openProgressFeed("9999"); err == nil {
+		t.Error("openProgressFeed() accepted a file descriptor that is not open")
+	}
+	for _, fd := range []string{"-1", "0", "1", "2"} {
+		if _, err := openProgressFeed(fd); err == nil {
+			t.Errorf("openProgressFeed(%s) accepted it", fd)
+		}
+	}
+}
+
+func TestHeldOutput(t *testing.T) {
+	stdoutFile, saved := os.Stdout, stdout
+	defer func() { os.Stdout, stdout = stdoutFile, saved }()
+
+	for _, found := range []bool{false, true} {
+		out, err := os.Create(filepath.Join(t.TempDir(), "stdout"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		os.Stdout = out
+
+		cfg := &Config{SilentUnlessFound: true}
+		if err := holdOutput(cfg); err != nil {
+			t.Fatal(err)
+		}
+		// Printing goes on while the output is let through
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fmt.Fprint(stdout, "scanning\n")
+		}()
+		wg.Wait()
+		if found {
+			cfg.held.release()
+			fmt.Fprint(stdout, "[+] 10.0.0.1\n")
+		}
+		cfg.held.discard()
+		cfg.held.release() // no-op once settled
+		out.Close()
+
+		want := ""
+		if found {
+			want = "scanning\n[+] 10.0.0.1\n"
+		}
+		if data, _ := os.ReadFile(out.Name()); string(data) != want {
+			t.Errorf("found=%v: stdout = %q, want %q", found, data, want)
+		}
+	}
+
+	t.Setenv("TMPDIR", filepath.Join(t.TempDir(), "missing"))
+	if err := holdOutput(&Config{SilentUnlessFound: true}); err == nil {
+		t.Error("holdOutput() without a usable temporary directory didn't fail")
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Weight of -passwords lines that don't give one
+const defaultPasswordWeight = 1
+
+// readPasswordFile loads a -passwords list. See parsePasswords for the format.
+func readPasswordFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return parsePasswords(f)
+}
+
+// parsePasswords reads one password per line, optionally followed by a tab
+// and a weight:
+//
+//	password<TAB>weight
+//
+// and returns them heaviest first, so the most common ones are tried early
+// on every host. Lines of equal weight, including all unweighted ones at
+// weight 1, keep their order in the file. Empty lines are skipped; there are
+// no comments, since a password may start with '#'.
+func parsePasswords(r io.Reader) ([]string, error) {
+	type weighted struct {
+		password string
+		weight   float64
+	}
+	var list []weighted
+
+	sc := bufio.NewScanner(r)
+	for lineNum := 1; sc.Scan(); lineNum++ {
+		line := strings.TrimSuffix(sc.Text(), "\r")
+		if line == "" {
+			continue
+		}
+
+		password, weightStr, hasWeight := strings.Cut(line, "\t")
+		weight := float64(defaultPasswordWeight)
+		if hasWeight {
+			w, err := strconv.ParseFloat(strings.TrimSpace(weightStr), 64)
+			if err != nil || w < 0 {
+				return nil, fmt.Errorf("line %d: invalid weight %q", lineNum, weightStr)
+			}
+			weight = w
+		}
+		list = append(list, weighted{password, weight})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	slices.SortStableFunc(list, func(a, b weighted) int {
+		return cmp.Compare(b.weight, a.weight)
+	})
+	passwords := make([]string, len(list))
+	for i, w := range list {
+		passwords[i] = w.password
+	}
+	return passwords, nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePasswords(t *testing.T) {
+	input := "rare\t0.5\nadmin\t50\n#hash\n\npassword\t30\nchangeme\nspace d\t30\r\n"
+	got, err := parsePasswords(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("parsePasswords() error = %v", err)
+	}
+	want := []string{"admin", "password", "space d", "#hash", "changeme", "rare"}
+	if !slices.Equal(got, want) {
+		t.Errorf("parsePasswords() = %q, want %q", got, want)
+	}
+
+	for _, bad := range []string{"admin\tmany", "admin\t-1"} {
+		if _, err := parsePasswords(strings.NewReader(bad)); err == nil {
+			t.Errorf("parsePasswords(%q) expected error", bad)
+		}
+	}
+}
+
+func TestScannerPasswordList(t *testing.T) {
+	addr, _ := startTestServer(t, "password")
+	host, portStr, _ := net.SplitHostPort(addr)
+	port, _ := strconv.Atoi(portStr)
+
+	cfg := &Config{Workers: 1, Port: port, User: "root", Password: "toor", Timeout: time.Second,
+		Passwords: []string{"admin", "password", "changeme"}}
+	got := runScanner(NewScanner(cfg), host)
+	if len(got) != 1 || !got[0].Success || got[0].Password != "password" {
+		t.Errorf("Run() = %+v, want success with the listed password", got)
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+)
+
+// errHostDown marks targets skipped because the host did not answer -ping.
+var errHostDown = errors.New("host down: no reply to -ping")
+
+// pinger sends ICMP echo requests over one shared socket and matches the
+// replies back to the waiting callers.
+type pinger struct {
+	conn *icmp.PacketConn
+
+	// Unprivileged datagram sockets address peers as *net.UDPAddr and the
+	// kernel rewrites the echo ID, so replies are matched on peer and sequence
+	datagram bool
+
+	id  int
+	seq atomic.Uint32
+
+	mu      sync.Mutex
+	waiting map[pingKey]chan struct{}
+}
+
+type pingKey struct {
+	ip  string
+	seq int
+}
+
+// newPinger opens a raw ICMP socket, or an unprivileged ICMP datagram socket
+// where the system allows it. The error means neither is permitted and
+// callers should fall back to a TCP connect check.
+func newPinger() (*pinger, error) {
+	p := &pinger{
+		id:      os.Getpid() & 0xffff,
+		waiting: make(map[pingKey]chan struct{}),
+	}
+
+	conn, err := icmp.ListenPacket("ip4:icmp", "0.0.0.0")
+	if err != nil {
+		var dgramErr error
+		if conn, dgramErr = icmp.ListenPacket("udp4", "0.0.0.0"); dgramErr != nil {
+			return nil, err
+		}
+		p.datagram = true
+	}
+	p.conn = conn
+
+	go p.readLoop()
+	return p, nil
+}
+
+func (p *pinger) Close() error {
+	return p.conn.Close()
+}
+
+// ping reports whether host answered an echo request within timeout. Hosts
+// that are not IPv4 addresses return an error so the caller can fall back.
+func (p *pinger) ping(host string, timeout time.Duration) (bool, error) {
+	ip := net.ParseIP(host).To4()
+	if ip == nil {
+		return false, fmt.Errorf("cannot ping %q over ICMPv4", host)
+	}
+
+	key := pingKey{ip.String(), int(p.seq.Add(1) & 0xffff)}
+	reply := make(chan struct{})
+	p.mu.Lock()
+	p.waiting[key] = reply
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		delete(p.waiting, key)
+		p.mu.Unlock()
+	}()
+
+	msg := icmp.Message{
+		Type: ipv4.ICMPTypeEcho,
+		Body: &icmp.Echo{ID: p.id, Seq: key.seq, Data: []byte("ssh-scanner")},
+	}
+	b, err := msg.Marshal(nil)
+	if err != nil {
+		return false, err
+	}
+
+	var dst net.Addr = &net.IPAddr{IP: ip}
+	if p.datagram {
+		dst = &net.UDPAddr{IP: ip}
+	}
+	if _, err := p.conn.WriteTo(b, dst); err != nil {
+		return false, err
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-reply:
+		return true, nil
+	case <-timer.C:
+		return false, nil
+	}
+}
+
+func (p *pinger) readLoop() {
+	buf := make([]byte, 1500)
+	for {
+		n, peer, err := p.conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+
+		msg, err := icmp.ParseMessage(ipv4.ICMPTypeEcho.Protocol(), buf[:n])
+		if err != nil || msg.Type != ipv4.ICMPTypeEchoReply {
+			continue
+		}
+		echo, ok := msg.Body.(*icmp.Echo)
+		if !ok || (!p.datagram && echo.ID != p.id) {
+			continue
+		}
+
+		var ip net.IP
+		switch addr := peer.(type) {
+		case *net.IPAddr:
+			ip = addr.IP
+		case *net.UDPAddr:
+			ip = addr.IP
+		}
+
+		p.mu.Lock()
+		if reply, ok := p.waiting[pingKey{ip.String(), echo.Seq}]; ok {
+			close(reply)
+			delete(p.waiting, pingKey{ip.String(), echo.Seq})
+		}
+		p.mu.Unlock()
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestPingerLoopback(t *testing.T) {
+	p, err := newPinger()
+	if err != nil {
+		t.Skipf("ICMP not permitted here: %v", err)
+	}
+	defer p.Close()
+
+	up, err := p.ping("127.0.0.1", time.Second)
+	if err != nil || !up {
+		t.Errorf("ping(127.0.0.1) = %v, %v; want true", up, err)
+	}
+	if _, err := p.ping("::1", time.Second); err == nil {
+		t.Errorf("ping(::1) succeeded, want an error so the caller falls back to TCP")
+	}
+}
+
+func TestScannerPingFallbackTreatsRefusedAsUp(t *testing.T) {
+	// Nothing listens on the port, but the refusal proves the host is there
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	cfg := &Config{Workers: 1, Port: port, Timeout: time.Second, Ping: 200 * time.Millisecond}
+	s := NewScanner(cfg)
+
+	var calls atomic.Int32
+	s.connect = func(addr string, config *ssh.ClientConfig) error {
+		calls.Add(1)
+		return nil
+	}
+
+	got := runScanner(s, "127.0.0.1")
+	if len(got) != 1 || !got[0].Success {
+		t.Fatalf("Run() = %+v, want one success", got)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("SSH connect ran %d times, want 1", calls.Load())
+	}
+	if stats := s.Stats(); stats.Down != 0 {
+		t.Errorf("Stats().Down = %d, want 0", stats.Down)
+	}
+}
+
+func TestScannerPingSkipsDownHosts(t *testing.T) {
+	// A proxy that accepts and never answers makes the fallback check time
+	// out the way a dead host would
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	cfg := &Config{Workers: 1, Port: 22, Timeout: time.Second, Ping: 100 * time.Millisecond}
+	cfg.HTTPProxy, _ = parseProxyURL(ln.Addr().String())
+	s := NewScanner(cfg)
+	s.connect = func(addr string, config *ssh.ClientConfig) error {
+		t.Errorf("SSH connect attempted on a down host")
+		return nil
+	}
+
+	got := runScanner(s, "10.0.0.1")
+	if len(got) != 1 || got[0].Category != failDown {
+		t.Fatalf("Run() = %+v, want one result categorized as down", got)
+	}
+	if stats := s.Stats(); stats.Down != 1 {
+		t.Errorf("Stats().Down = %d, want 1", stats.Down)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// presets bundle flag values for common kinds of scan, for -fast,
+// -thorough and -internet.
+var presets = map[string]map[string]string{
+	// Many workers that don't wait long and skip closed ports cheaply
+	"fast": {
+		"w":        "500",
+		"t":        "1s",
+		"precheck": "300ms",
+		"retries":  "0",
+	},
+	// Fewer workers with the patience and algorithms for slow, old devices
+	"thorough": {
+		"w":             "50",
+		"t":             "10s",
+		"retries":       "2",
+		"hostkey-algos": strings.Join(hostKeyAlgorithms(), ","),
+	},
+	// Gentle on networks we don't own: few connections, spread out, and
+	// none to a host that has started refusing us
+	"internet": {
+		"w":           strconv.Itoa(internetMaxWorkers),
+		"per-subnet":  strconv.Itoa(internetMaxPerSubnet),
+		"ramp":        "30s",
+		"randomize":   "true",
+		"cred-policy": credStopOnBan,
+	},
+}
+
+// Ceilings -internet enforces; flags may go below them but not above
+const (
+	internetMaxWorkers   = 50
+	internetMaxPerSubnet = 1
+)
+
+// checkInternetLimits rejects settings that would make an -internet scan
+// heavier than the mode allows, including explicit flags and -json-config
+// values that override its preset.
+func checkInternetLimits(cfg *Config) error {
+	switch {
+	case cfg.Workers > internetMaxWorkers:
+		return fmt.Errorf("-w %d is over the limit of %d", cfg.Workers, internetMaxWorkers)
+	case cfg.PerSubnet < 1 || cfg.PerSubnet > internetMaxPerSubnet:
+		return fmt.Errorf("-per-subnet must be between 1 and %d", internetMaxPerSubnet)
+	case !cfg.Randomize:
+		return errors.New("-randomize can't be turned off")
+	case cfg.CredPolicy != credStopOnBan:
+		return fmt.Errorf("-cred-policy must be %s", credStopOnBan)
+	}
+	return nil
+}
+
+// applyPreset sets the flags of the named preset on fs, leaving alone the
+// ones given on the command line so they override the preset.
+func applyPreset(fs *flag.FlagSet, name string) error {
+	given := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+	values := presets[name]
+	for _, flagName := range slices.Sorted(maps.Keys(values)) {
+		if given[flagName] {
+			continue
+		}
+		if err := fs.Set(flagName, values[flagName]); err != nil {
+			return fmt.Errorf("-%s: %v", flagName, err)
+		}
+	}
+	return nil
+}
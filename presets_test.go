@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestApplyPreset(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	workers := fs.String("w", "100", "")
+	timeout := fs.Duration("t", 3*time.Second, "")
+	precheck := fs.Duration("precheck", 0, "")
+	retries := fs.Int("retries", 0, "")
+	if err := fs.Parse([]string{"-t", "2s"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := applyPreset(fs, "fast"); err != nil {
+		t.Fatal(err)
+	}
+	if *workers != "500" || *precheck != 300*time.Millisecond || *retries != 0 {
+		t.Errorf("-fast set -w %s -precheck %v -retries %d", *workers, *precheck, *retries)
+	}
+	if *timeout != 2*time.Second {
+		t.Errorf("-fast overrode the explicit -t 2s with %v", *timeout)
+	}
+}
+
+func TestCheckInternetLimits(t *testing.T) {
+	safe := Config{Workers: 50, PerSubnet: 1, Randomize: true, CredPolicy: credStopOnBan}
+	if err := checkInternetLimits(&safe); err != nil {
+		t.Errorf("checkInternetLimits(preset values) = %v", err)
+	}
+	lower := safe
+	lower.Workers = 10
+	if err := checkInternetLimits(&lower); err != nil {
+		t.Errorf("checkInternetLimits(-w 10) = %v, want lower settings allowed", err)
+	}
+
+	for name, change := range map[string]func(*Config){
+		"-w 200":                  func(c *Config) { c.Workers = 200 },
+		"-per-subnet 0":           func(c *Config) { c.PerSubnet = 0 },
+		"-per-subnet 4":           func(c *Config) { c.PerSubnet = 4 },
+		"-randomize=false":        func(c *Config) { c.Randomize = false },
+		"-cred-policy exhaustive": func(c *Config) { c.CredPolicy = credExhaustive },
+	} {
+		cfg := safe
+		change(&cfg)
+		if err := checkInternetLimits(&cfg); err == nil {
+			t.Errorf("checkInternetLimits(%s) accepted it", name)
+		}
+	}
+}
+
+func TestCheckInternetLimitsJSONConfig(t *testing.T) {
+	for _, job := range []string{
+		`{"targets":["10.0.0.0/24"],"workers":500}`,
+		`{"targets":["10.0.0.0/24"],"per_subnet":4}`,
+	} {
+		path := filepath.Join(t.TempDir(), "job.json")
+		if err := os.WriteFile(path, []byte(job), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		cfg := Config{Internet: true, Workers: 50, PerSubnet: 1, Randomize: true, CredPolicy: credStopOnBan}
+		var algos string
+		if err := loadJSONConfig(&cfg, path, &algos); err != nil {
+			t.Fatalf("loadJSONConfig(%s) = %v", job, err)
+		}
+		if err := checkInternetLimits(&cfg); err == nil {
+			t.Errorf("checkInternetLimits() accepted -internet with -json-config %s", job)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// startConnectProxy runs a minimal HTTP CONNECT proxy that requires the
+// given Proxy-Authorization header value.
+func startConnectProxy(t *testing.T, wantAuth string) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				req, err := http.ReadRequest(bufio.NewReader(conn))
+				if err != nil || req.Method != http.MethodConnect {
+					return
+				}
+				if req.Header.Get("Proxy-Authorization") != wantAuth {
+					io.WriteString(conn, "HTTP/1.1 407 Proxy Authentication Required\r\n\r\n")
+					return
+				}
+				upstream, err := net.Dial("tcp", req.Host)
+				if err != nil {
+					io.WriteString(conn, "HTTP/1.1 502 Bad Gateway\r\n\r\n")
+					return
+				}
+				defer upstream.Close()
+				io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+				go io.Copy(upstream, conn)
+				io.Copy(conn, upstream)
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestScannerHTTPProxy(t *testing.T) {
+	sshAddr, _ := startTestServer(t, "toor")
+	host, portStr, _ := net.SplitHostPort(sshAddr)
+	port, _ := strconv.Atoi(portStr)
+
+	// "user:pass" in base64
+	proxyAddr := startConnectProxy(t, "Basic dXNlcjpwYXNz")
+
+	for _, tt := range []struct {
+		proxy   string
+		success bool
+	}{
+		{"http://user:pass@" + proxyAddr, true},
+		{"http://user:wrong@" + proxyAddr, false},
+	} {
+		proxy, err := parseProxyURL(tt.proxy)
+		if err != nil {
+			t.Fatalf("parseProxyURL(%q) error = %v", tt.proxy, err)
+		}
+		cfg := &Config{Workers: 1, Port: port, User: "root", Password: "toor", Timeout: time.Second, HTTPProxy: proxy}
+
+		got := runScanner(NewScanner(cfg), host)
+		if len(got) != 1 || got[0].Success != tt.success {
+			t.Errorf("Run() via %s = %+v, want success %v", tt.proxy, got, tt.success)
+		} else if got[0].Route != "http-proxy "+proxyAddr {
+			t.Errorf("Route via %s = %q, want the proxy without credentials", tt.proxy, got[0].Route)
+		}
+	}
+
+	cfg := &Config{Workers: 1, Port: port, User: "root", Password: "toor", Timeout: time.Second}
+	if got := runScanner(NewScanner(cfg), host); len(got) != 1 || got[0].Route != "direct" {
+		t.Errorf("Run() without a proxy = %+v, want route direct", got)
+	}
+}
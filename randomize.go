@@ -0,0 +1,120 @@
+package main
+
+import (
+	"math/bits"
+	"math/rand/v2"
+	"net"
+	"sort"
+)
+
+// randomRangeTargets is rangeTargets with the addresses of all specs in a
+// random order, for -randomize: consecutive attempts land far apart instead
+// of walking one subnet after another. The ports of each address stay
+// together. The order is a fixed permutation drawn from seed, so the same
+// -seed repeats it and -state can resume it.
+func randomRangeTargets(specs []string, ports []int, buffer int, seed uint64) (<-chan Target, uint64, error) {
+	if len(ports) == 0 {
+		ports = []int{0}
+	}
+
+	var (
+		bases  []net.IP
+		starts []uint64 // index of each network's first address
+		hosts  uint64
+	)
+	for _, spec := range expandShortcuts(specs) {
+		ip, ipNet, err := parseInput(spec)
+		if err != nil {
+			return nil, 0, err
+		}
+		base := ip.Mask(ipNet.Mask)
+		if base4 := base.To4(); base4 != nil {
+			base = base4
+		}
+		bases = append(bases, base)
+		starts = append(starts, hosts)
+		hosts += countIPs(ipNet)
+	}
+	total := hosts * uint64(len(ports))
+
+	perm := newPermutation(hosts, seed)
+	buffer = int(min(uint64(buffer), 2*max(total, 1)))
+	out := make(chan Target, buffer)
+	go func() {
+		defer close(out)
+		for i := range hosts {
+			n := perm.at(i)
+			// The last network starting at or before n holds it
+			j := sort.Search(len(starts), func(k int) bool { return starts[k] > n }) - 1
+			host := offsetIP(bases[j], n-starts[j]).String()
+			for _, port := range ports {
+				out <- Target{Host: host, Port: port}
+			}
+		}
+	}()
+	return out, total, nil
+}
+
+// shuffleTargets puts an -iL target list in a random order for -randomize,
+// the same one for the same seed.
+func shuffleTargets(targets []Target, seed uint64) {
+	r := rand.New(rand.NewPCG(seed, 0))
+	r.Shuffle(len(targets), func(i, j int) {
+		targets[i], targets[j] = targets[j], targets[i]
+	})
+}
+
+// permutation maps 0..n-1 onto itself as i -> (a*i + c) mod n, with a
+// coprime to n so every index comes up exactly once. It is no cryptographic
+// shuffle, but it spreads neighbouring indexes across the whole range with
+// no memory, whatever its size.
+type permutation struct {
+	n, a, c uint64
+}
+
+func newPermutation(n, seed uint64) permutation {
+	if n < 2 {
+		return permutation{n: n, a: 1}
+	}
+	r := rand.New(rand.NewPCG(seed, n))
+	p := permutation{n: n, c: r.Uint64N(n)}
+	for {
+		// Skip strides so small the order still visibly walks the range
+		p.a = r.Uint64N(n-1) + 1
+		if gcd(p.a, n) == 1 && (n < 16 || (p.a > n/8 && p.a < n-n/8)) {
+			return p
+		}
+	}
+}
+
+func (p permutation) at(i uint64) uint64 {
+	if p.n < 2 {
+		return i
+	}
+	hi, lo := bits.Mul64(p.a, i)
+	m := bits.Rem64(hi, lo, p.n)
+	// m + c mod n, without overflowing for ranges near 2^64
+	if m >= p.n-p.c {
+		return m - (p.n - p.c)
+	}
+	return m + p.c
+}
+
+func gcd(a, b uint64) uint64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// offsetIP returns the address n places after base.
+func offsetIP(base net.IP, n uint64) net.IP {
+	ip := make(net.IP, len(base))
+	copy(ip, base)
+	for j := len(ip) - 1; j >= 0 && n > 0; j-- {
+		sum := uint64(ip[j]) + n&0xff
+		ip[j] = byte(sum)
+		n = n>>8 + sum>>8
+	}
+	return ip
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"net"
+	"slices"
+	"testing"
+)
+
+func TestPermutation(t *testing.T) {
+	for _, n := range []uint64{1, 2, 7, 16, 256, 1000} {
+		p := newPermutation(n, 42)
+		seen := make([]bool, n)
+		for i := range n {
+			j := p.at(i)
+			if j >= n || seen[j] {
+				t.Fatalf("n=%d: at(%d) = %d, repeated or out of range", n, i, j)
+			}
+			seen[j] = true
+		}
+	}
+
+	// Neighbouring indexes land far apart, not a subnet walk
+	p := newPermutation(1<<16, 42)
+	if d := int64(p.at(1)) - int64(p.at(0)); d > -1<<13 && d < 1<<13 {
+		t.Errorf("at(0), at(1) = %d, %d; want them far apart", p.at(0), p.at(1))
+	}
+	// Large ranges don't overflow
+	big := newPermutation(1<<40+3, 7)
+	if j := big.at(1<<40 + 2); j >= 1<<40+3 {
+		t.Errorf("at(last) = %d, out of range", j)
+	}
+}
+
+func TestRandomRangeTargets(t *testing.T) {
+	collect := func(targets <-chan Target) []string {
+		var hosts []string
+		for target := range targets {
+			hosts = append(hosts, net.JoinHostPort(target.Host, "22"))
+		}
+		return hosts
+	}
+	specs := []string{"10.0.0.0/28", "10.0.1.250/31", "10.0.2.1"}
+
+	ordered, want, err := rangeTargets(specs, nil, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	random, total, err := randomRangeTargets(specs, nil, 100, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, got := collect(ordered), collect(random)
+	if total != want || uint64(len(got)) != total {
+		t.Fatalf("randomRangeTargets() = %d targets, total %d; want %d", len(got), total, want)
+	}
+	if slices.Equal(got, plain) {
+		t.Error("randomRangeTargets() kept the range order")
+	}
+	slices.Sort(got)
+	slices.Sort(plain)
+	if !slices.Equal(got, plain) {
+		t.Errorf("randomRangeTargets() = %v, want the addresses of %v", got, specs)
+	}
+
+	drain := func(targets <-chan Target) []Target {
+		var list []Target
+		for target := range targets {
+			list = append(list, target)
+		}
+		return list
+	}
+	first, _, _ := randomRangeTargets(specs, []int{22, 2222}, 100, 42)
+	again, _, _ := randomRangeTargets(specs, []int{22, 2222}, 100, 42)
+	a, b := drain(first), drain(again)
+	if !slices.Equal(a, b) {
+		t.Error("same -seed gave different orders")
+	}
+	for i := 0; i < len(a); i += 2 {
+		if a[i].Host != a[i+1].Host {
+			t.Fatalf("targets %d-%d = %v, %v; want the ports of a host together", i, i+1, a[i], a[i+1])
+		}
+	}
+}
+
+func TestOffsetIP(t *testing.T) {
+	tests := []struct {
+		base string
+		n    uint64
+		want string
+	}{
+		{"10.0.0.0", 0, "10.0.0.0"},
+		{"10.0.0.250", 10, "10.0.1.4"},
+		{"10.0.0.0", 1 << 16, "10.1.0.0"},
+		{"2001:db8::", 0x1_0000, "2001:db8::1:0"},
+	}
+	for _, tt := range tests {
+		base := net.ParseIP(tt.base)
+		if b4 := base.To4(); b4 != nil {
+			base = b4
+		}
+		if got := offsetIP(base, tt.n).String(); got != tt.want {
+			t.Errorf("offsetIP(%s, %d) = %s, want %s", tt.base, tt.n, got, tt.want)
+		}
+	}
+}
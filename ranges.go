@@ -0,0 +1,204 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// splitTargets splits a comma-separated target argument such as
+// "1.2.3.4,5.6.7.8,10.0.0.0/29" into its elements, each checked like a
+// single CIDR, IP or shortcut.
+func splitTargets(arg string) ([]string, error) {
+	specs := strings.Split(arg, ",")
+	for i, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		specs[i] = spec
+		for _, sub := range expandShortcut(spec) {
+			if _, _, err := parseInput(sub); err != nil {
+				return nil, fmt.Errorf("element %d (%q): %v", i+1, spec, err)
+			}
+		}
+	}
+	return specs, nil
+}
+
+// parseRangeExpr evaluates a -targets expression such as
+//
+//	10.0.0.0/8 - 10.1.0.0/16 - 10.2.0.0/16 + 192.168.1.0/24
+//
+// Terms are anything parseInput accepts, separated by spaces or commas. A
+// term preceded by "-" is excluded, everything else is included; the result
+// is the union of the includes minus the union of the excludes, returned as
+// disjoint CIDR blocks in the order the includes were given.
+func parseRangeExpr(expr string) ([]string, error) {
+	var includes, excludes []netip.Prefix
+
+	exclude := false
+	for _, tok := range strings.FieldsFunc(expr, func(r rune) bool { return r == ' ' || r == ',' || r == '\t' }) {
+		switch tok {
+		case "-":
+			exclude = true
+			continue
+		case "+":
+			exclude = false
+			continue
+		}
+		if rest, ok := strings.CutPrefix(tok, "-"); ok {
+			tok, exclude = rest, true
+		} else if rest, ok := strings.CutPrefix(tok, "+"); ok {
+			tok, exclude = rest, false
+		}
+
+		prefixes, err := termPrefixes(tok)
+		if err != nil {
+			return nil, err
+		}
+		if exclude {
+			excludes = append(excludes, prefixes...)
+		} else {
+			includes = append(includes, prefixes...)
+		}
+		exclude = false
+	}
+	if len(includes) == 0 {
+		return nil, errors.New("no ranges to include")
+	}
+
+	// Earlier includes are subtracted from later ones so that overlapping
+	// terms don't scan any address twice
+	var blocks []netip.Prefix
+	for _, inc := range includes {
+		pieces := []netip.Prefix{inc}
+		for _, ex := range append(excludes, blocks...) {
+			pieces = subtractPrefix(pieces, ex)
+		}
+		blocks = append(blocks, pieces...)
+	}
+
+	specs := make([]string, len(blocks))
+	for i, b := range blocks {
+		specs[i] = b.String()
+	}
+	return specs, nil
+}
+
+// termPrefixes parses one range term, expanding shortcuts, into prefixes.
+func termPrefixes(tok string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, term := range expandShortcut(tok) {
+		_, ipNet, err := parseInput(term)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %v", tok, err)
+		}
+		ones, _ := ipNet.Mask.Size()
+		addr, _ := netip.AddrFromSlice(ipNet.IP)
+		prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), ones))
+	}
+	return prefixes, nil
+}
+
+// parsePriority parses the comma-separated -priority list.
+func parsePriority(list string) ([]netip.Prefix, error) {
+	var priority []netip.Prefix
+	for _, tok := range strings.Split(list, ",") {
+		prefixes, err := termPrefixes(strings.TrimSpace(tok))
+		if err != nil {
+			return nil, err
+		}
+		priority = append(priority, prefixes...)
+	}
+	return priority, nil
+}
+
+// prioritizeRanges reorders specs for -priority: the parts of them that lie
+// in a priority range come first, in the order of the priority ranges, then
+// the remainder in the order given. The blocks returned are disjoint, so no
+// address is scanned twice even where the specs overlap.
+func prioritizeRanges(specs []string, priority []netip.Prefix) ([]string, error) {
+	var ranges []netip.Prefix
+	for _, spec := range specs {
+		prefixes, err := termPrefixes(spec)
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, prefixes...)
+	}
+
+	var blocks []netip.Prefix
+	add := func(p netip.Prefix) {
+		pieces := []netip.Prefix{p}
+		for _, b := range blocks {
+			pieces = subtractPrefix(pieces, b)
+		}
+		blocks = append(blocks, pieces...)
+	}
+	for _, pri := range priority {
+		for _, r := range ranges {
+			if !r.Overlaps(pri) {
+				continue
+			}
+			// Overlapping prefixes nest, so the overlap is the longer one
+			inner := pri
+			if r.Bits() > pri.Bits() {
+				inner = r
+			}
+			add(inner)
+		}
+	}
+	for _, r := range ranges {
+		add(r)
+	}
+
+	out := make([]string, len(blocks))
+	for i, b := range blocks {
+		out[i] = b.String()
+	}
+	return out, nil
+}
+
+// prioritizeTargets moves the targets inside a -priority range to the front,
+// keeping the order within both groups.
+func prioritizeTargets(targets []Target, priority []netip.Prefix) []Target {
+	first := make([]Target, 0, len(targets))
+	var rest []Target
+	for _, t := range targets {
+		addr, err := netip.ParseAddr(t.Host)
+		if err == nil && slices.ContainsFunc(priority, func(p netip.Prefix) bool { return p.Contains(addr.Unmap()) }) {
+			first = append(first, t)
+		} else {
+			rest = append(rest, t)
+		}
+	}
+	return append(first, rest...)
+}
+
+// subtractPrefix removes ex from every prefix in blocks. A block that
+// contains ex is split in halves until the remainder is made of prefixes
+// that don't overlap it, so excluding a /16 from a /8 yields eight blocks.
+func subtractPrefix(blocks []netip.Prefix, ex netip.Prefix) []netip.Prefix {
+	var out []netip.Prefix
+	for _, b := range blocks {
+		switch {
+		case !b.Overlaps(ex):
+			out = append(out, b)
+		case ex.Bits() <= b.Bits():
+			// ex covers all of b
+		default:
+			lo, hi := splitPrefix(b)
+			out = append(out, subtractPrefix([]netip.Prefix{lo, hi}, ex)...)
+		}
+	}
+	return out
+}
+
+// splitPrefix returns the two halves of p, which must not be a single address.
+func splitPrefix(p netip.Prefix) (netip.Prefix, netip.Prefix) {
+	bits := p.Bits()
+	a := p.Addr().AsSlice()
+	a[bits/8] |= 0x80 >> (bits % 8)
+	hi, _ := netip.AddrFromSlice(a)
+	return netip.PrefixFrom(p.Addr(), bits+1), netip.PrefixFrom(hi, bits+1)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitTargets(t *testing.T) {
+	got, err := splitTargets("1.2.3.4, 5.6.7.8,10.0.0.0/29,3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"1.2.3.4", "5.6.7.8", "10.0.0.0/29", "3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("splitTargets() = %v, want %v", got, want)
+	}
+
+	for _, bad := range []string{"1.2.3.4,bogus", "1.2.3.4,,5.6.7.8"} {
+		if _, err := splitTargets(bad); err == nil || !strings.Contains(err.Error(), "element 2") {
+			t.Errorf("splitTargets(%q) error = %v, want one naming element 2", bad, err)
+		}
+	}
+}
+
+func TestParseRangeExpr(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{"10.0.0.0/24", []string{"10.0.0.0/24"}},
+		{"10.0.0.0/24 - 10.0.0.0/25", []string{"10.0.0.128/25"}},
+		{"10.0.0.0/8 - 10.1.0.0/16", []string{
+			"10.0.0.0/16", "10.2.0.0/15", "10.4.0.0/14", "10.8.0.0/13",
+			"10.16.0.0/12", "10.32.0.0/11", "10.64.0.0/10", "10.128.0.0/9",
+		}},
+		{"10.0.0.0/30,-10.0.0.1", []string{"10.0.0.0/32", "10.0.0.2/31"}},
+		{"3 + 10.0.0.0/31 - 192.168.3.128/25", []string{"192.168.3.0/25", "10.0.0.0/31"}},
+		{"10.0.0.0/24 10.0.0.0/25", []string{"10.0.0.0/24"}},
+		{"10.0.0.0/24 - 10.0.0.0/16", nil},
+		{"fd00::/126 - fd00::1", []string{"fd00::/128", "fd00::2/127"}},
+	}
+	for _, tt := range tests {
+		got, err := parseRangeExpr(tt.expr)
+		if err != nil {
+			t.Errorf("parseRangeExpr(%q) error = %v", tt.expr, err)
+			continue
+		}
+		if len(got) == 0 && len(tt.want) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseRangeExpr(%q) = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"", "- 10.0.0.0/8", "10.0.0.0/8 - nope"} {
+		if _, err := parseRangeExpr(bad); err == nil {
+			t.Errorf("parseRangeExpr(%q) expected error", bad)
+		}
+	}
+}
+
+func TestPrioritizeRanges(t *testing.T) {
+	priority, err := parsePriority("10.0.1.0/24, 10.0.0.128/25, 172.16.0.0/12")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := prioritizeRanges([]string{"10.0.0.0/23", "10.0.1.0/25", "172.16.5.5"}, priority)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Priority parts first, then the rest; the overlapping 10.0.1.0/25 is
+	// already covered and the unscanned part of 172.16.0.0/12 is ignored
+	want := []string{"10.0.1.0/24", "10.0.0.128/25", "172.16.5.5/32", "10.0.0.0/25"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("prioritizeRanges() = %v, want %v", got, want)
+	}
+
+	if _, err := parsePriority("10.0.0.0/8,nope"); err == nil {
+		t.Error("parsePriority(nope) expected error")
+	}
+}
+
+func TestPrioritizeTargets(t *testing.T) {
+	priority, _ := parsePriority("10.9.0.0/16")
+	targets := []Target{{Host: "10.0.0.1"}, {Host: "10.9.0.1"}, {Host: "db.example.com"}, {Host: "10.9.3.3", Port: 2222}}
+
+	got := prioritizeTargets(targets, priority)
+	want := []Target{{Host: "10.9.0.1"}, {Host: "10.9.3.3", Port: 2222}, {Host: "10.0.0.1"}, {Host: "db.example.com"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("prioritizeTargets() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// readReplayFile reads the findings of an earlier run for -replay: an -o file
+// in text format, which uses the -iL line format, or a -json log, of which the
+// successful results are taken. Results that don't record a password of their
+// own were found with the run's -u and -p, which the targets then fall back
+// to.
+func readReplayFile(path string) ([]Target, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return parseTargets(bytes.NewReader(data))
+	}
+
+	var targets []Target
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(nil, 1<<20) // -audit-algos makes for long lines
+	for lineNum := 1; sc.Scan(); lineNum++ {
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+		var res struct {
+			IP       string `json:"ip"`
+			Port     int    `json:"port"`
+			User     string `json:"user"`
+			Password string `json:"password"`
+			Success  bool   `json:"success"`
+			Label    string `json:"label"`
+		}
+		if err := json.Unmarshal(sc.Bytes(), &res); err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNum, err)
+		}
+		if !res.Success {
+			continue
+		}
+		t := Target{Host: res.IP, Port: res.Port, Label: res.Label}
+		if res.Password != "" {
+			t.User, t.Password = res.User, res.Password
+		}
+		targets = append(targets, t)
+	}
+	return targets, sc.Err()
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadReplayFile(t *testing.T) {
+	dir := t.TempDir()
+	for _, tt := range []struct {
+		name, data string
+		want       []Target
+	}{
+		{
+			"found.txt",
+			"10.0.0.1\n10.0.0.2:2222 admin Winter2026\n",
+			[]Target{{Host: "10.0.0.1"}, {Host: "10.0.0.2", Port: 2222, User: "admin", Password: "Winter2026"}},
+		},
+		{
+			"scan.jsonl",
+			`{"ip":"10.0.0.1","port":22,"user":"root","success":true}` + "\n" +
+				`{"ip":"10.0.0.2","port":22,"user":"root","success":false,"category":"auth"}` + "\n" +
+				`{"ip":"10.0.0.3","port":2222,"user":"admin","password":"admin123","success":true}` + "\n",
+			[]Target{{Host: "10.0.0.1", Port: 22}, {Host: "10.0.0.3", Port: 2222, User: "admin", Password: "admin123"}},
+		},
+	} {
+		path := filepath.Join(dir, tt.name)
+		if err := os.WriteFile(path, []byte(tt.data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		got, err := readReplayFile(path)
+		if err != nil {
+			t.Fatalf("readReplayFile(%s) error = %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("readReplayFile(%s) = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DNS lookups run on their own small pool, separate from the SSH workers, each
+// bounded by a timeout. These are the defaults of -dns-workers and
+// -dns-timeout.
+const (
+	resolveWorkers = 8
+	resolveTimeout = 2 * time.Second
+)
+
+// lookupAddrFunc matches net.Resolver.LookupAddr; tests swap in a fake.
+type lookupAddrFunc func(ctx context.Context, addr string) ([]string, error)
+
+// lookupIPFunc matches net.Resolver.LookupIPAddr.
+type lookupIPFunc func(ctx context.Context, host string) ([]net.IPAddr, error)
+
+// dnsWorkers is the number of concurrent DNS lookups: -dns-workers, or
+// resolveWorkers by default.
+func (cfg *Config) dnsWorkers() int {
+	if cfg.DNSWorkers > 0 {
+		return cfg.DNSWorkers
+	}
+	return resolveWorkers
+}
+
+// dnsTimeout bounds each DNS lookup: -dns-timeout, or resolveTimeout by
+// default.
+func (cfg *Config) dnsTimeout() time.Duration {
+	if cfg.DNSTimeout > 0 {
+		return cfg.DNSTimeout
+	}
+	return resolveTimeout
+}
+
+// resolveNames passes results through, filling in Hostname for the
+// successful ones from their PTR record. Lookups happen off the scan's
+// workers, so a slow resolver delays the report and never the scan; hosts
+// without a PTR record, or whose lookup times out, are passed on unnamed.
+// Results may come out in a different order than they went in.
+func resolveNames(in <-chan Result, lookup lookupAddrFunc, cfg *Config) <-chan Result {
+	workers := cfg.dnsWorkers()
+	out := make(chan Result, workers)
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for res := range in {
+				if res.Success && net.ParseIP(res.IP) != nil {
+					res.Hostname = reverseName(lookup, res.IP, cfg.dnsTimeout())
+				}
+				out <- res
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+func reverseName(lookup lookupAddrFunc, ip string, timeout time.Duration) string {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	names, err := lookup(ctx, ip)
+	if err != nil || len(names) == 0 {
+		return ""
+	}
+	return strings.TrimSuffix(names[0], ".")
+}
+
+// lookupHost resolves a hostname target before it is dialed. Lookups share
+// the scanner's DNS pool, so thousands of SSH workers dialing hostnames at
+// once don't flood the resolver.
+func (s *Scanner) lookupHost(host string) ([]net.IPAddr, error) {
+	select {
+	case s.dns <- struct{}{}:
+	case <-s.ctx.Done():
+		return nil, s.ctx.Err()
+	}
+	defer func() { <-s.dns }()
+
+	ctx, cancel := context.WithTimeout(s.ctx, s.cfg.dnsTimeout())
+	defer cancel()
+	return s.lookupIP(ctx, host)
+}
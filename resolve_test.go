@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestResolveNames(t *testing.T) {
+	var lookups atomic.Int32
+	lookup := func(ctx context.Context, addr string) ([]string, error) {
+		lookups.Add(1)
+		if addr == "10.0.0.1" {
+			return []string{"web01.example.com."}, nil
+		}
+		return nil, errors.New("no PTR record")
+	}
+
+	in := make(chan Result, 3)
+	in <- Result{IP: "10.0.0.1", Success: true}
+	in <- Result{IP: "10.0.0.2", Success: true}
+	in <- Result{IP: "10.0.0.3"}
+	close(in)
+
+	got := make(map[string]string)
+	for res := range resolveNames(in, lookup, &Config{}) {
+		got[res.IP] = res.Hostname
+	}
+
+	want := map[string]string{"10.0.0.1": "web01.example.com", "10.0.0.2": "", "10.0.0.3": ""}
+	if len(got) != len(want) {
+		t.Fatalf("resolveNames() passed %d results, want %d", len(got), len(want))
+	}
+	for ip, name := range want {
+		if got[ip] != name {
+			t.Errorf("Hostname of %s = %q, want %q", ip, got[ip], name)
+		}
+	}
+	// Failed attempts are passed through without a lookup
+	if n := lookups.Load(); n != 2 {
+		t.Errorf("made %d lookups, want 2", n)
+	}
+}
+
+func TestScannerLookupHostPool(t *testing.T) {
+	addr, _ := startTestServer(t, "toor")
+	_, portStr, _ := net.SplitHostPort(addr)
+	port, _ := strconv.Atoi(portStr)
+
+	var active, peak atomic.Int32
+	cfg := &Config{Workers: 20, Port: port, User: "root", Password: "toor", Timeout: time.Second, DNSWorkers: 2}
+	s := NewScanner(cfg)
+	s.lookupIP = func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		n := active.Add(1)
+		defer active.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		return []net.IPAddr{{IP: net.IPv4(127, 0, 0, 1)}}, nil
+	}
+
+	var hosts []string
+	for i := range 10 {
+		hosts = append(hosts, fmt.Sprintf("host%d.test", i))
+	}
+	got := runScanner(s, hosts...)
+
+	found := 0
+	for _, res := range got {
+		if res.Success {
+			found++
+		}
+	}
+	if found != len(hosts) {
+		t.Errorf("found %d of %d hostname targets: %+v", found, len(hosts), got)
+	}
+	if p := peak.Load(); p > 2 {
+		t.Errorf("%d concurrent lookups, want at most -dns-workers 2", p)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"math"
+	"math/rand/v2"
+)
+
+// sampleSize is the number of hosts a -sample rate keeps out of hosts. A
+// non-empty range keeps at least one.
+func sampleSize(hosts uint64, rate float64) uint64 {
+	if hosts == 0 {
+		return 0
+	}
+	return min(hosts, max(1, uint64(math.Round(float64(hosts)*rate))))
+}
+
+// sampleTargets passes on a random subset of the hosts in a range stream for
+// -sample, with all ports of each kept host. hosts is the number of distinct
+// hosts in targets, which must arrive grouped by host as rangeTargets makes
+// them. Selection sampling keeps exactly sampleSize of them, spread over the
+// whole range, and the choice depends only on seed and the range, so the same
+// -seed picks the same hosts again. The returned total counts the targets
+// that will be produced.
+func sampleTargets(targets <-chan Target, hosts uint64, ports int, rate float64, seed uint64) (<-chan Target, uint64) {
+	want := sampleSize(hosts, rate)
+	out := make(chan Target, cap(targets))
+	go func() {
+		defer close(out)
+		r := rand.New(rand.NewPCG(seed, 0))
+		var (
+			seen, kept uint64
+			last       string
+			keep       bool
+		)
+		for t := range targets {
+			if seen == 0 || t.Host != last {
+				keep = kept < want && seen < hosts && r.Uint64N(hosts-seen) < want-kept
+				if keep {
+					kept++
+				}
+				last = t.Host
+				seen++
+			}
+			if keep {
+				out <- t
+			}
+		}
+	}()
+	return out, want * uint64(max(ports, 1))
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSampleTargets(t *testing.T) {
+	sample := func(seed uint64) ([]Target, uint64) {
+		targets, total, err := rangeTargets([]string{"10.0.0.0/22"}, []int{22, 2222}, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sampled, total := sampleTargets(targets, total/2, 2, 0.1, seed)
+		var got []Target
+		for target := range sampled {
+			got = append(got, target)
+		}
+		return got, total
+	}
+
+	got, total := sample(7)
+	if total != 2*102 || uint64(len(got)) != total {
+		t.Fatalf("sampleTargets(/22, 0.1) produced %d targets, total %d; want %d", len(got), total, 2*102)
+	}
+	for i := 0; i < len(got); i += 2 {
+		if got[i].Host != got[i+1].Host || got[i].Port != 22 || got[i+1].Port != 2222 {
+			t.Fatalf("targets %d-%d = %+v, %+v; want both ports of one host", i, i+1, got[i], got[i+1])
+		}
+	}
+	// Spread over the range rather than its first addresses
+	if last := got[len(got)-1].Host; last < "10.0.2.0" {
+		t.Errorf("last sampled host %s, want one from the end of the range", last)
+	}
+
+	again, _ := sample(7)
+	if !slices.Equal(got, again) {
+		t.Error("same -seed picked different hosts")
+	}
+	if other, _ := sample(8); slices.Equal(got, other) {
+		t.Error("another -seed picked the same hosts")
+	}
+}
+
+func TestSampleSize(t *testing.T) {
+	tests := []struct {
+		hosts uint64
+		rate  float64
+		want  uint64
+	}{
+		{0, 0.5, 0},
+		{1, 0.01, 1},
+		{256, 0.01, 3},
+		{1 << 24, 0.01, 167772},
+		{10, 0.99, 10},
+	}
+	for _, tt := range tests {
+		if got := sampleSize(tt.hosts, tt.rate); got != tt.want {
+			t.Errorf("sampleSize(%d, %v) = %d, want %d", tt.hosts, tt.rate, got, tt.want)
+		}
+	}
+}
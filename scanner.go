@@ -0,0 +1,984 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Upper bound on targets held for a later requeue round
+const maxRequeued = 1 << 16
+
+// Result is the outcome of a single SSH attempt against one target.
+type Result struct {
+	IP       string        `json:"ip"`
+	Port     int           `json:"port"`
+	User     string        `json:"user"`
+	Success  bool          `json:"success"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+
+	// Run and -tag the result came from
+	ScanID string `json:"scan_id,omitempty"`
+	Tag    string `json:"tag,omitempty"`
+
+	// Label of the target's row in an -iL CSV file
+	Label string `json:"label,omitempty"`
+
+	// ID sent in the identification string, set by -attempt-log
+	AttemptID string `json:"attempt_id,omitempty"`
+
+	Category failureCategory `json:"category,omitempty"`
+
+	// How the target was reached: "direct" or through -http-proxy, see
+	// routeLabel
+	Route string `json:"route,omitempty"`
+
+	// SSH identification string of the server, set by -detect-ssh and
+	// -banner-match
+	Banner string `json:"banner,omitempty"`
+
+	// Banner the server showed before the login, usually a legal warning,
+	// set by -auth-banner whether or not the credentials were accepted
+	AuthBanner string `json:"auth_banner,omitempty"`
+
+	// PTR name of the host, set for found hosts by -resolve-names
+	Hostname string `json:"hostname,omitempty"`
+
+	// SHA256 fingerprint of the host key, set whenever the handshake got that far
+	Fingerprint string `json:"fingerprint,omitempty"`
+
+	// Set by -legacy-fallback when the server shared no modern algorithm
+	// with the client and was tried again offering the legacy ones
+	Legacy bool `json:"legacy,omitempty"`
+
+	// Password that was accepted, set when -heuristic-creds found one other
+	// than the configured password
+	Password string `json:"password,omitempty"`
+
+	// Auth methods the server offers, set by -probe-auth-methods. "none"
+	// means it let us in without credentials.
+	AuthMethods []string `json:"auth_methods,omitempty"`
+
+	// Algorithms the server advertises, and the weak ones among them, set
+	// by -audit-algos for every host that got as far as its KEXINIT
+	Algorithms     *Algorithms `json:"algorithms,omitempty"`
+	WeakAlgorithms []string    `json:"weak_algorithms,omitempty"`
+
+	// What the login is good for, set by -verify-shell on found hosts:
+	// "shell" or "auth-only"
+	Access string `json:"access,omitempty"`
+
+	err            error
+	target         Target
+	precheckFailed bool
+	down           bool
+	notSSH         bool
+	skipped        bool
+	saturated      bool
+	unmatched      bool
+	known          bool
+}
+
+// Stats is a point-in-time snapshot of the scanner counters.
+type Stats struct {
+	Processed int32 `json:"processed"`
+	Found     int32 `json:"found"`
+	Failed    int32 `json:"failed"`
+	SlowPass  int32 `json:"slow_pass,omitempty"`
+
+	// Retry attempts made, and hosts that were only found on a retry
+	Retries      int32 `json:"retries,omitempty"`
+	RetrySuccess int32 `json:"retry_success,omitempty"`
+
+	// Failures caused by the local side having no route to the target
+	Unreachable int32 `json:"unreachable"`
+
+	// Targets skipped because the TCP pre-check failed
+	Closed int32 `json:"closed,omitempty"`
+
+	// Targets whose SSH server got as far as presenting a host key, whether
+	// or not the credentials were accepted
+	SSH int32 `json:"ssh,omitempty"`
+
+	// Open ports skipped by -detect-ssh because they run something else
+	NotSSH int32 `json:"not_ssh,omitempty"`
+
+	// Targets skipped because the host did not answer -ping
+	Down int32 `json:"down,omitempty"`
+
+	// Ports skipped by -connect-once because another port on the host was
+	// unreachable
+	Skipped int32 `json:"skipped,omitempty"`
+
+	// Targets skipped because -max-found-per-subnet hosts were already
+	// found in their subnet
+	Saturated int32 `json:"saturated,omitempty"`
+
+	// SSH servers skipped because their banner did not match -banner-match
+	Unmatched int32 `json:"unmatched,omitempty"`
+
+	// Targets skipped because -found-once found them in an earlier run
+	Known int32 `json:"known,omitempty"`
+
+	// Time spent waiting outside the -window, paused by the operator or by
+	// -breaker
+	Paused time.Duration `json:"paused"`
+
+	// Attempts currently in flight
+	Active int32 `json:"active"`
+
+	// Attempts aborted by cancelling the scan; they count as neither
+	// processed nor failed
+	Cancelled int32 `json:"cancelled,omitempty"`
+
+	// Hosts that seem to have banned the scanner and were given up on
+	Banned int32 `json:"banned,omitempty"`
+
+	// Passwords sent when hosts get more than -p, and the connections they
+	// took; several share a connection as far as MaxAuthTries allows
+	Passwords     int32 `json:"passwords,omitempty"`
+	PasswordConns int32 `json:"password_conns,omitempty"`
+}
+
+// Scanner performs concurrent SSH login attempts and reports one Result per target.
+type Scanner struct {
+	cfg *Config
+
+	// connect is swapped out in tests to avoid real network traffic
+	connect func(addr string, config *ssh.ClientConfig) error
+
+	// lookupIP resolves hostname targets, limited to -dns-workers at a time
+	// by dns
+	lookupIP lookupIPFunc
+	dns      chan struct{}
+
+	// sem bounds concurrent attempts across all phases of a Run to Workers,
+	// and Config.Limiter, if set, across every scanner sharing it
+	sem *Limiter
+
+	// subnets additionally bounds attempts per /24 when -per-subnet is set
+	subnets *subnetLimiter
+
+	// ctx is the context of the current Run; cancelling it aborts the dials
+	// and handshakes in flight
+	ctx context.Context
+
+	// stop cancels ctx once -max-attempts connections were opened
+	stop    context.CancelFunc
+	dialNum atomic.Int64
+	capped  atomic.Bool
+
+	processedNum    atomic.Int32
+	okNum, failNum  atomic.Int32
+	slowNum         atomic.Int32
+	retryNum        atomic.Int32
+	retryOkNum      atomic.Int32
+	unreachableNum  atomic.Int32
+	closedNum       atomic.Int32
+	downNum         atomic.Int32
+	sshNum          atomic.Int32
+	notSSHNum       atomic.Int32
+	skippedNum      atomic.Int32
+	saturatedNum    atomic.Int32
+	unmatchedNum    atomic.Int32
+	knownNum        atomic.Int32
+	passwordNum     atomic.Int32
+	passwordConnNum atomic.Int32
+	pausedNs        atomic.Int64
+	activeNum       atomic.Int32
+	cancelledNum    atomic.Int32
+	sourceNext      atomic.Uint32
+	categoryNum     [numFailureCategories]atomic.Int32
+
+	// latency buckets the duration of every attempt that dialled
+	latency latencyHistogram
+
+	// hits counts the accepted passwords for -learn-creds
+	hits passwordHits
+
+	bans banWatch
+
+	// breaker pauses the scan when -breaker trips
+	breaker *circuitBreaker
+
+	// probes holds back a host's other ports under -connect-once
+	probes hostProbes
+
+	// quota counts the hosts found per subnet for -max-found-per-subnet
+	quota subnetQuota
+
+	// known holds the hosts earlier runs found, which -found-once skips
+	known map[string]bool
+
+	// attempts records the ID of every attempt for -attempt-log
+	attempts *attemptLog
+
+	// resume is non-nil while the scan is paused and closed by Resume
+	pauseMu  sync.Mutex
+	resume   chan struct{}
+	pausedAt time.Time
+}
+
+func NewScanner(cfg *Config) *Scanner {
+	s := &Scanner{cfg: cfg, ctx: context.Background(), dns: make(chan struct{}, cfg.dnsWorkers())}
+	s.connect = s.tryConnectSSH
+	s.lookupIP = net.DefaultResolver.LookupIPAddr
+	return s
+}
+
+// Stats returns the current counters. It is safe to call while Run is active.
+func (s *Scanner) Stats() Stats {
+	return Stats{
+		Processed: s.processedNum.Load(),
+		Found:     s.okNum.Load(),
+		Failed:    s.failNum.Load(),
+		SlowPass:  s.slowNum.Load(),
+
+		Retries:      s.retryNum.Load(),
+		RetrySuccess: s.retryOkNum.Load(),
+
+		Unreachable: s.unreachableNum.Load(),
+		Closed:      s.closedNum.Load(),
+		SSH:         s.sshNum.Load(),
+		NotSSH:      s.notSSHNum.Load(),
+		Down:        s.downNum.Load(),
+		Skipped:     s.skippedNum.Load(),
+		Saturated:   s.saturatedNum.Load(),
+		Unmatched:   s.unmatchedNum.Load(),
+		Known:       s.knownNum.Load(),
+		Paused:      time.Duration(s.pausedNs.Load()),
+		Active:      s.activeNum.Load(),
+		Cancelled:   s.cancelledNum.Load(),
+		Banned:      int32(s.bans.count()),
+
+		Passwords:     s.passwordNum.Load(),
+		PasswordConns: s.passwordConnNum.Load(),
+	}
+}
+
+// Latency summarizes the attempt durations so far as p50, p90 and p99, or
+// returns "" before any attempt finished.
+func (s *Scanner) Latency() string {
+	return latencySummary(&s.latency)
+}
+
+// AttemptsCapped reports whether the scan was stopped by -max-attempts.
+func (s *Scanner) AttemptsCapped() bool {
+	return s.capped.Load()
+}
+
+// FailureCounts returns the number of failed targets per failure category.
+func (s *Scanner) FailureCounts() map[failureCategory]int32 {
+	counts := make(map[failureCategory]int32)
+	for c := failNone + 1; c < numFailureCategories; c++ {
+		if n := s.categoryNum[c].Load(); n > 0 {
+			counts[c] = n
+		}
+	}
+	return counts
+}
+
+// Pause stops dispatching new attempts until Resume is called. Attempts
+// already in flight still complete.
+func (s *Scanner) Pause() {
+	s.pauseMu.Lock()
+	defer s.pauseMu.Unlock()
+	if s.resume == nil {
+		s.resume = make(chan struct{})
+		s.pausedAt = time.Now()
+	}
+}
+
+// Resume continues a scan stopped by Pause.
+func (s *Scanner) Resume() {
+	s.pauseMu.Lock()
+	defer s.pauseMu.Unlock()
+	if s.resume != nil {
+		close(s.resume)
+		s.resume = nil
+		s.pausedNs.Add(int64(time.Since(s.pausedAt)))
+	}
+}
+
+// Paused reports whether Pause is in effect.
+func (s *Scanner) Paused() bool {
+	s.pauseMu.Lock()
+	defer s.pauseMu.Unlock()
+	return s.resume != nil
+}
+
+// tripBreaker pauses the scan after -breaker tripped and resumes it after
+// -breaker-cooldown, unless it was resumed early and tripped again since.
+func (s *Scanner) tripBreaker() {
+	s.Pause()
+	trips, _ := s.breaker.state()
+	time.AfterFunc(s.cfg.BreakerCooldown, func() {
+		if now, _ := s.breaker.state(); now == trips {
+			s.Resume()
+		}
+	})
+}
+
+// Breaker reports how often -breaker paused the scan and until when the last
+// trip holds it. Both are zero without -breaker.
+func (s *Scanner) Breaker() (trips int, openUntil time.Time) {
+	if s.breaker == nil {
+		return 0, time.Time{}
+	}
+	return s.breaker.state()
+}
+
+func (s *Scanner) waitResume(ctx context.Context) {
+	s.pauseMu.Lock()
+	resume := s.resume
+	s.pauseMu.Unlock()
+
+	if resume != nil {
+		select {
+		case <-resume:
+		case <-ctx.Done():
+		}
+	}
+}
+
+// Run attempts every target received from targets and sends the outcome to results.
+// It closes results once all attempts have finished. Cancelling ctx stops
+// dispatching new targets and aborts the attempts in flight; those were never
+// settled, so they are only counted in Stats.Cancelled and not reported.
+//
+// Hosts that need another attempt are not retried inline. They go to a
+// requeue list that is worked through in further rounds once the current
+// round has drained, so retries never hold up fresh targets:
+//
+//   - with a fast timeout configured, hosts that time out in the first round
+//     get one more try with the full timeout (the tiered slow pass);
+//   - transient failures are retried up to -retries times per host.
+func (s *Scanner) Run(ctx context.Context, targets <-chan Target, results chan<- Result) {
+	defer close(results)
+
+	if s.cfg.MaxAttempts > 0 {
+		ctx, s.stop = context.WithCancel(ctx)
+		defer s.stop()
+	}
+	s.ctx = ctx
+	s.sem = NewLimiter(s.cfg.Workers)
+	if s.cfg.PerSubnet > 0 {
+		s.subnets = newSubnetLimiter(s.cfg.PerSubnet)
+	}
+	if s.cfg.Ramp > 0 && s.cfg.Workers > 1 {
+		// Park all but one token before any worker can start
+		reserved := s.cfg.Workers - 1
+		for range reserved {
+			s.sem.Acquire(context.Background())
+		}
+		stop := make(chan struct{})
+		defer close(stop)
+		go s.rampUp(reserved, stop)
+	}
+
+	if s.cfg.Breaker > 0 {
+		s.breaker = newCircuitBreaker(s.cfg.Breaker, s.cfg.BreakerWindow, s.cfg.BreakerCooldown)
+	}
+
+	var (
+		requeueMu sync.Mutex
+		requeued  []Target
+		handle    func(Result)
+	)
+	handle = func(res Result) {
+		if res.Category == failCancelled {
+			s.cancelledNum.Add(1)
+			return
+		}
+		// Timed before the requeue so the retried attempts count too
+		if !res.down && !res.skipped && !res.saturated && !res.known {
+			s.latency.add(res.Duration)
+			if s.breaker != nil && s.breaker.add(pathFailure(res.Category), time.Now()) {
+				s.tripBreaker()
+			}
+		}
+		if t, ok := s.requeue(res); ok {
+			requeueMu.Lock()
+			if len(requeued) < maxRequeued {
+				requeued = append(requeued, t)
+				requeueMu.Unlock()
+				return
+			}
+			requeueMu.Unlock()
+
+			// The requeue list is full; retry right away in this worker so
+			// memory stays bounded even when most of a /8 times out
+			handle(s.safeAttempt(t, s.cfg.Timeout))
+			return
+		}
+		if res.Success && s.cfg.LearnCreds {
+			password := res.Password
+			if password == "" {
+				password = res.target.Password
+			}
+			s.hits.add(password)
+		}
+		if res.Category == failBanned {
+			s.bans.ban(res.target.Host)
+		}
+		if res.Success && s.cfg.MaxFoundPerSubnet > 0 {
+			s.quota.add(res.target.Host)
+		}
+		s.record(res)
+		results <- res
+	}
+
+	timeout := s.cfg.Timeout
+	if s.tiered() {
+		timeout = s.cfg.FastTimeout
+	}
+	s.runPhase(ctx, targets, timeout, handle)
+
+	for len(requeued) > 0 && ctx.Err() == nil {
+		round := requeued
+		requeued = nil
+		s.runPhase(ctx, sliceTargets(round), s.cfg.Timeout, handle)
+	}
+}
+
+// retryable reports whether -retries applies to failures of category c.
+func (s *Scanner) retryable(c failureCategory) bool {
+	retryOn := s.cfg.RetryOn
+	if retryOn == nil {
+		retryOn = defaultRetryOn
+	}
+	return slices.Contains(retryOn, c)
+}
+
+func (s *Scanner) tiered() bool {
+	return s.cfg.FastTimeout > 0 && s.cfg.FastTimeout < s.cfg.Timeout
+}
+
+// requeue decides whether a failed attempt deserves another round and
+// returns the target to requeue with its bookkeeping updated.
+func (s *Scanner) requeue(res Result) (Target, bool) {
+	t := res.target
+	switch {
+	case res.err == nil:
+		return t, false
+	case s.tiered() && !t.slowPass && isTimeout(res.err):
+		// The slow pass is part of tiering and does not use up a retry
+		t.slowPass = true
+		s.slowNum.Add(1)
+		return t, true
+	case t.retries < s.cfg.Retries && s.retryable(res.Category):
+		t.retries++
+		s.retryNum.Add(1)
+		return t, true
+	}
+	return t, false
+}
+
+// runPhase attempts every target with the given timeout, bounded by the
+// worker count, and passes each result to handle from the worker goroutine.
+func (s *Scanner) runPhase(ctx context.Context, targets <-chan Target, timeout time.Duration, handle func(Result)) {
+	var wg sync.WaitGroup
+
+loop:
+	for t := range targets {
+		s.waitResume(ctx)
+		if s.cfg.Window != nil {
+			s.pausedNs.Add(int64(s.cfg.Window.wait(ctx)))
+		}
+
+		var subnet string
+		if s.subnets != nil {
+			key, ok, err := s.subnets.acquire(ctx, t)
+			if err != nil {
+				break loop
+			}
+			if !ok {
+				// Parked until an attempt in its subnet hands it the slot
+				continue
+			}
+			subnet = key
+		}
+		release, err := s.acquire(ctx)
+		// Either acquire may have blocked until after the window closed; give
+		// the slot back while waiting for it to open again
+		for err == nil && s.cfg.Window != nil && !s.cfg.Window.contains(time.Now()) {
+			release()
+			s.pausedNs.Add(int64(s.cfg.Window.wait(ctx)))
+			release, err = s.acquire(ctx)
+		}
+		if err != nil {
+			if s.subnets != nil {
+				s.subnets.release(subnet, false)
+			}
+			break loop
+		}
+		wg.Add(1)
+		go func(target Target) {
+			defer wg.Done()
+			defer release()
+			s.activeNum.Add(1)
+			defer s.activeNum.Add(-1)
+			for {
+				handle(s.safeAttempt(target, timeout))
+				if s.subnets == nil {
+					return
+				}
+				// Go on with a target parked in the same subnet, if any
+				next, ok := s.subnets.release(subnet, ctx.Err() == nil)
+				if !ok {
+					return
+				}
+				s.waitResume(ctx)
+				if s.cfg.Window != nil {
+					s.pausedNs.Add(int64(s.cfg.Window.wait(ctx)))
+				}
+				if ctx.Err() != nil {
+					s.subnets.release(subnet, false)
+					return
+				}
+				target = next
+			}
+		}(t)
+	}
+
+	// Unblock the generator if we stopped early
+	go func() {
+		for range targets {
+		}
+	}()
+
+	wg.Wait()
+}
+
+// acquire takes the worker token and then the shared slot an attempt needs,
+// and returns the function that gives them back. The worker token comes
+// first so this scanner never holds more than Workers shared slots. A
+// -per-subnet slot is taken before either, see subnetLimiter, so a
+// saturated subnet never sits on a worker another subnet could use.
+func (s *Scanner) acquire(ctx context.Context) (func(), error) {
+	if err := s.sem.Acquire(ctx); err != nil {
+		return nil, err
+	}
+	shared := s.cfg.Limiter
+	if shared != nil {
+		if err := shared.Acquire(ctx); err != nil {
+			s.sem.Release()
+			return nil, err
+		}
+	}
+	return func() {
+		if shared != nil {
+			shared.Release()
+		}
+		s.sem.Release()
+	}, nil
+}
+
+// Shortest interval between two tokens released by rampUp; a -ramp too short
+// to give every worker this long takes a little longer than asked
+const minRampStep = time.Millisecond
+
+// rampUp releases the reserved semaphore tokens evenly over the ramp
+// duration so concurrency grows linearly up to the full worker count.
+func (s *Scanner) rampUp(reserved int, stop <-chan struct{}) {
+	ticker := time.NewTicker(max(s.cfg.Ramp/time.Duration(reserved), minRampStep))
+	defer ticker.Stop()
+	for range reserved {
+		select {
+		case <-ticker.C:
+			s.sem.Release()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// errAttemptCap refuses connections past -max-attempts. It counts as a
+// cancellation: the target was never settled, only cut off.
+var errAttemptCap = fmt.Errorf("-max-attempts reached: %w", context.Canceled)
+
+// errPanic marks attempts that panicked, such as on a malformed server reply.
+var errPanic = errors.New("panic")
+
+// safeAttempt runs attempt, turning a panic into a failed result so that one
+// bad host neither crashes the scan nor loses the output collected so far.
+func (s *Scanner) safeAttempt(t Target, timeout time.Duration) (res Result) {
+	defer func() {
+		if r := recover(); r != nil {
+			t := s.resolve(t)
+			res = Result{IP: t.Host, Port: t.Port, User: t.User, ScanID: s.cfg.ScanID, Tag: s.cfg.Tag, Label: t.Label, target: t}
+			res.err = fmt.Errorf("%w: %v", errPanic, r)
+			res.Error = res.err.Error()
+			res.Category = failPanic
+		}
+	}()
+	return s.attempt(t, timeout)
+}
+
+func (s *Scanner) attempt(t Target, timeout time.Duration) Result {
+	t = s.resolve(t)
+	start := time.Now()
+	res := s.try(t, timeout)
+	if s.cfg.LegacyFallback && !t.legacy && isNoCommonAlgorithm(res.err) {
+		// Nothing modern in common: try once more with everything
+		// crypto/ssh still speaks, and keep to that on later retries
+		t.legacy = true
+		res = s.try(t, timeout)
+	}
+	res.Duration = time.Since(start)
+	res.Legacy = t.legacy
+
+	if res.err == nil {
+		res.Success = true
+	} else {
+		res.Error = res.err.Error()
+		res.Category = classifyError(res.err)
+	}
+	return res
+}
+
+// tryTarget runs the pre-check, if any, and the SSH attempt for t, filling
+// in the details it learns along the way.
+func (s *Scanner) tryTarget(t Target, timeout time.Duration, res *Result) error {
+	addr := net.JoinHostPort(t.Host, strconv.Itoa(t.Port))
+
+	if s.known[foundLabel(Result{IP: t.Host, Port: t.Port}, s.cfg)] {
+		res.known = true
+		return errKnownFound
+	}
+	if s.bans.isBanned(t.Host) {
+		return fmt.Errorf("%w: skipped, banned earlier in the scan", errBanned)
+	}
+	if s.cfg.MaxFoundPerSubnet > 0 && s.quota.full(t.Host, s.cfg.MaxFoundPerSubnet) {
+		res.saturated = true
+		return errSubnetDone
+	}
+	if s.cfg.Ping > 0 && !s.hostUp(t.Host, addr) {
+		res.down = true
+		return errHostDown
+	}
+	if len(s.cfg.Knock) > 0 {
+		// Before the pre-check, which would find the port still shut
+		if err := s.knock(t.Host); err != nil {
+			return err
+		}
+	}
+
+	// A cheap TCP connect weeds out closed and filtered ports before we
+	// spend a full SSH timeout on them. -detect-ssh goes one step further
+	// and also waits for the server's identification string.
+	switch {
+	case s.cfg.DetectSSH:
+		timeout := s.cfg.Precheck
+		if timeout == 0 {
+			timeout = s.cfg.Timeout
+		}
+		banner, err := s.checkBanner(addr, timeout)
+		if err != nil {
+			if errors.Is(err, errNotSSH) {
+				res.notSSH = true
+			} else {
+				res.precheckFailed = true
+			}
+			return err
+		}
+		res.Banner = banner
+		if s.cfg.BannerMatch != nil && !s.cfg.BannerMatch.MatchString(banner) {
+			res.unmatched = true
+			return fmt.Errorf("%w: %q", errBannerMismatch, banner)
+		}
+	case s.cfg.Precheck > 0:
+		conn, err := s.dial(addr, s.cfg.Precheck)
+		if err != nil {
+			res.precheckFailed = true
+			return err
+		}
+		conn.Close()
+	}
+
+	if s.cfg.AuditAlgos {
+		// A failure here shows again in the SSH attempt itself
+		if algos, err := s.readAlgorithms(addr, timeout); err == nil {
+			res.Algorithms = algos
+			res.WeakAlgorithms = algos.weak()
+		}
+	}
+
+	config := s.clientConfig(t, timeout)
+	s.tagAttempt(t, config, res)
+	config.HostKeyCallback = func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		res.Fingerprint = ssh.FingerprintSHA256(key)
+		return nil
+	}
+	if s.cfg.AuthBanner {
+		// Every connection shows the same banner, so the last one stands
+		config.BannerCallback = func(message string) error {
+			res.AuthBanner = message
+			return nil
+		}
+	}
+	if s.cfg.morePasswords() {
+		bases := append([]string{t.Password}, s.cfg.Passwords...)
+		if s.cfg.HeuristicCreds {
+			bases = append(bases, heuristicPasswords(t.Host)...)
+		}
+		candidates := s.cfg.Mangle.candidates(bases, time.Now().Year())
+		if s.cfg.ShuffleCreds {
+			candidates = shuffledFor(candidates, t.Host, s.cfg.Seed)
+		}
+		// Passwords known to work elsewhere still go first
+		if s.cfg.LearnCreds {
+			candidates = learnedFirst(s.hits.top(learnedTop), candidates)
+		}
+		password, err := s.tryPasswords(addr, config, candidates)
+		if err == nil && password != t.Password {
+			res.Password = password
+		}
+		if err == nil && s.cfg.VerifyShell {
+			res.Access = s.verifyShell(addr, config, password)
+		}
+		return err
+	}
+	if s.cfg.NoneAuth {
+		// With no methods to offer, crypto/ssh only sends the initial "none"
+		// request and gives up when the server wants anything else
+		config.Auth = nil
+		err := s.connectVerified(addr, config, res)
+		if err == nil {
+			res.AuthMethods = []string{"none"}
+		}
+		return err
+	}
+	if !s.cfg.ProbeAuth {
+		return s.connectVerified(addr, config, res)
+	}
+
+	// Learning the offered methods is the success condition of a probe
+	var methods []string
+	config.Auth = probeAuth(&methods)
+	err := s.connect(addr, config)
+	if err == nil {
+		methods = []string{"none"}
+	}
+	res.AuthMethods = methods
+	if len(methods) > 0 {
+		return nil
+	}
+	return err
+}
+
+// hostUp pings host, or when ICMP is unavailable checks whether addr answers
+// a TCP connect at all. A refused connection still proves the host is up.
+func (s *Scanner) hostUp(host, addr string) bool {
+	if s.cfg.Pinger != nil {
+		if up, err := s.cfg.Pinger.ping(host, s.cfg.Ping); err == nil {
+			return up
+		}
+	}
+
+	conn, err := s.dial(addr, s.cfg.Ping)
+	if err != nil {
+		return errors.Is(err, syscall.ECONNREFUSED)
+	}
+	conn.Close()
+	return true
+}
+
+// try makes one attempt on a resolved target, leaving the outcome in the
+// returned Result's err.
+func (s *Scanner) try(t Target, timeout time.Duration) Result {
+	res := Result{IP: t.Host, Port: t.Port, User: t.User, ScanID: s.cfg.ScanID, Tag: s.cfg.Tag,
+		Label: t.Label, Route: routeLabel(s.cfg.HTTPProxy), target: t}
+	if s.cfg.ConnectOnce && t.retries == 0 && !t.slowPass {
+		res.err = s.probeTarget(t, timeout, &res)
+	} else {
+		res.err = s.tryTarget(t, timeout, &res)
+	}
+	return res
+}
+
+// resolve fills the fields a target leaves empty from the global configuration.
+func (s *Scanner) resolve(t Target) Target {
+	if t.Port == 0 {
+		t.Port = s.cfg.Port
+	}
+	if t.User == "" {
+		t.User = s.cfg.User
+		t.Password = s.cfg.Password
+	}
+	return t
+}
+
+// record updates the counters for a final result.
+func (s *Scanner) record(res Result) {
+	if res.Fingerprint != "" {
+		s.sshNum.Add(1)
+	}
+	if res.Success {
+		s.okNum.Add(1)
+		if res.target.retries > 0 {
+			s.retryOkNum.Add(1)
+		}
+	} else {
+		s.failNum.Add(1)
+		s.categoryNum[res.Category].Add(1)
+		if isUnreachable(res.err) {
+			s.unreachableNum.Add(1)
+		}
+		if res.precheckFailed {
+			s.closedNum.Add(1)
+		}
+		if res.down {
+			s.downNum.Add(1)
+		}
+		if res.notSSH {
+			s.notSSHNum.Add(1)
+		}
+		if res.skipped {
+			s.skippedNum.Add(1)
+		}
+		if res.saturated {
+			s.saturatedNum.Add(1)
+		}
+		if res.unmatched {
+			s.unmatchedNum.Add(1)
+		}
+		if res.known {
+			s.knownNum.Add(1)
+		}
+	}
+	s.processedNum.Add(1)
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func (s *Scanner) clientConfig(t Target, timeout time.Duration) *ssh.ClientConfig {
+	auth := []ssh.AuthMethod{ssh.Password(t.Password)}
+	if s.cfg.Signer != nil {
+		auth = []ssh.AuthMethod{ssh.PublicKeys(s.cfg.Signer)}
+	}
+
+	config := &ssh.ClientConfig{
+		User:              t.User,
+		Auth:              auth,
+		HostKeyCallback:   ssh.InsecureIgnoreHostKey(),
+		HostKeyAlgorithms: s.cfg.HostKeyAlgorithms,
+		ClientVersion:     cmp.Or(s.cfg.ClientVersion, defaultClientVersion),
+		Timeout:           timeout,
+	}
+	if t.legacy {
+		useLegacyAlgorithms(config)
+	}
+	return config
+}
+
+// isUnreachable reports whether err means the packet never left this host,
+// which usually points at a local network problem rather than the target.
+func isUnreachable(err error) bool {
+	return errors.Is(err, syscall.ENETUNREACH) || errors.Is(err, syscall.EHOSTUNREACH)
+}
+
+func (s *Scanner) tryConnectSSH(addr string, config *ssh.ClientConfig) error {
+	conn, err := s.dial(addr, config.Timeout)
+	if err != nil {
+		return err
+	}
+	defer context.AfterFunc(s.ctx, func() { conn.Close() })()
+	if s.cfg.BannerTimeout > 0 {
+		conn = newBannerConn(conn, s.cfg.BannerTimeout)
+	}
+
+	expired := s.authDeadline(conn)
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	timedOut := expired()
+	if err != nil {
+		conn.Close()
+		if s.ctx.Err() != nil {
+			// The handshake failed because the connection was closed under it
+			return s.ctx.Err()
+		}
+		if timedOut {
+			return errAuthTimeout
+		}
+		return err
+	}
+	ssh.NewClient(c, chans, reqs).Close()
+	return nil
+}
+
+// errAuthTimeout reports a login cut short by -auth-timeout. It counts as a
+// timeout, though the host did accept the connection.
+var errAuthTimeout = fmt.Errorf("%w: no login within -auth-timeout", os.ErrDeadlineExceeded)
+
+// authDeadline bounds the SSH handshake and login on conn by -auth-timeout,
+// closing conn when it runs out. A timer rather than a deadline on conn, as
+// the -banner-timeout wrapper resets the read deadline. The returned func
+// stops the timer and reports whether it had already fired.
+func (s *Scanner) authDeadline(conn net.Conn) (expired func() bool) {
+	if s.cfg.AuthTimeout <= 0 {
+		return func() bool { return false }
+	}
+	var fired atomic.Bool
+	timer := time.AfterFunc(s.cfg.AuthTimeout, func() {
+		fired.Store(true)
+		conn.Close()
+	})
+	return func() bool {
+		timer.Stop()
+		return fired.Load()
+	}
+}
+
+// dial opens the transport for one attempt, tunnelling through the HTTP
+// proxy when one is configured.
+func (s *Scanner) dial(addr string, timeout time.Duration) (net.Conn, error) {
+	if s.stop != nil && s.dialNum.Add(1) > s.cfg.MaxAttempts {
+		s.capped.Store(true)
+		s.stop()
+		return nil, errAttemptCap
+	}
+	d := &net.Dialer{Timeout: timeout, KeepAlive: s.cfg.KeepAlive}
+	if s.cfg.HTTPProxy != nil {
+		if src := s.sourceAddr(s.cfg.HTTPProxy.Hostname()); src != nil {
+			d.LocalAddr = src
+		}
+		return dialHTTPProxy(s.ctx, d, s.cfg.HTTPProxy, addr)
+	}
+	host, port, _ := net.SplitHostPort(addr)
+	if src := s.sourceAddr(host); src != nil {
+		d.LocalAddr = src
+	}
+	if net.ParseIP(host) != nil {
+		conn, err := d.DialContext(s.ctx, "tcp", addr)
+		return s.checkBan(addr, conn, err)
+	}
+
+	// Resolve on the DNS pool, then try the addresses in order as the dialer
+	// itself would
+	ips, err := s.lookupHost(host)
+	if err != nil {
+		return nil, err
+	}
+	var conn net.Conn
+	for _, ip := range ips {
+		if conn, err = d.DialContext(s.ctx, "tcp", net.JoinHostPort(ip.String(), port)); err == nil {
+			break
+		}
+	}
+	return s.checkBan(addr, conn, err)
+}
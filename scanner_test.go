@@ -0,0 +1,690 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// runScanner runs s over ips and collects every result.
+func runScanner(s *Scanner, ips ...string) []Result {
+	in := make(chan Target, len(ips))
+	for _, ip := range ips {
+		in <- Target{Host: ip}
+	}
+	close(in)
+
+	out := make(chan Result)
+	go s.Run(context.Background(), in, out)
+
+	var got []Result
+	for res := range out {
+		got = append(got, res)
+	}
+	return got
+}
+
+func TestScannerTieredTimeout(t *testing.T) {
+	cfg := &Config{Workers: 4, Port: 22, Timeout: time.Second, FastTimeout: 10 * time.Millisecond}
+	s := NewScanner(cfg)
+
+	// 10.0.0.1 answers quickly, 10.0.0.2 only within the full timeout
+	// and 10.0.0.3 refuses outright.
+	s.connect = func(addr string, config *ssh.ClientConfig) error {
+		switch {
+		case strings.HasPrefix(addr, "10.0.0.1:"):
+			return nil
+		case strings.HasPrefix(addr, "10.0.0.2:"):
+			if config.Timeout < time.Second {
+				return timeoutError{}
+			}
+			return nil
+		default:
+			return errors.New("connection refused")
+		}
+	}
+
+	got := runScanner(s, "10.0.0.1", "10.0.0.2", "10.0.0.3")
+	if len(got) != 3 {
+		t.Fatalf("Run() produced %d results, want 3", len(got))
+	}
+
+	stats := s.Stats()
+	want := Stats{Processed: 3, Found: 2, Failed: 1, SlowPass: 1}
+	if stats != want {
+		t.Errorf("Stats() = %+v, want %+v", stats, want)
+	}
+}
+
+func TestScannerRecoversFromPanic(t *testing.T) {
+	s := NewScanner(&Config{Workers: 2, Port: 22, Timeout: time.Second})
+	s.connect = func(addr string, config *ssh.ClientConfig) error {
+		if strings.HasPrefix(addr, "10.0.0.2:") {
+			var banner []string
+			_ = banner[3] // a parser bug tripped by one odd host
+		}
+		return nil
+	}
+
+	got := runScanner(s, "10.0.0.1", "10.0.0.2", "10.0.0.3")
+	if len(got) != 3 {
+		t.Fatalf("Run() produced %d results, want all 3", len(got))
+	}
+	for _, res := range got {
+		if res.IP == "10.0.0.2" {
+			if res.Success || res.Category != failPanic || !strings.Contains(res.Error, "index out of range") || res.Port != 22 {
+				t.Errorf("panicking host: %+v, want a failed result in the panic category", res)
+			}
+		} else if !res.Success {
+			t.Errorf("%s: %+v, want success", res.IP, res)
+		}
+	}
+	if stats := s.Stats(); stats.Found != 2 || stats.Failed != 1 {
+		t.Errorf("Stats() = %+v, want 2 found and 1 failed", stats)
+	}
+}
+
+func TestIsUnreachable(t *testing.T) {
+	dialErr := func(errno syscall.Errno) error {
+		return &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", errno)}
+	}
+
+	if !isUnreachable(dialErr(syscall.ENETUNREACH)) || !isUnreachable(dialErr(syscall.EHOSTUNREACH)) {
+		t.Errorf("isUnreachable() = false for unreachable dial errors")
+	}
+	if isUnreachable(dialErr(syscall.ECONNREFUSED)) || isUnreachable(timeoutError{}) {
+		t.Errorf("isUnreachable() = true for an error from a reachable network")
+	}
+}
+
+// startTestServer runs an in-process SSH server on localhost that accepts
+// user "root" with the given password, and returns its address and host key.
+func startTestServer(t *testing.T, password string) (string, ssh.PublicKey) {
+	t.Helper()
+
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+			if conn.User() == "root" && string(pass) == password {
+				return nil, nil
+			}
+			return nil, errors.New("access denied")
+		},
+	}
+	return startSSHServer(t, config)
+}
+
+// startSSHServer serves config on a loopback port with a fresh host key.
+func startSSHServer(t *testing.T, config *ssh.ServerConfig) (string, ssh.PublicKey) {
+	t.Helper()
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.AddHostKey(signer)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, chans, reqs, err := ssh.NewServerConn(conn, config)
+				if err != nil {
+					return
+				}
+				go ssh.DiscardRequests(reqs)
+				for ch := range chans {
+					ch.Reject(ssh.Prohibited, "no channels")
+				}
+			}()
+		}
+	}()
+
+	return ln.Addr().String(), signer.PublicKey()
+}
+
+func TestScannerFingerprint(t *testing.T) {
+	addr, hostKey := startTestServer(t, "toor")
+	host, portStr, _ := net.SplitHostPort(addr)
+	port, _ := strconv.Atoi(portStr)
+
+	cfg := &Config{Workers: 1, Port: port, User: "root", Password: "toor", Timeout: time.Second}
+	got := runScanner(NewScanner(cfg), host)
+
+	if len(got) != 1 || !got[0].Success {
+		t.Fatalf("Run() = %+v, want one successful result", got)
+	}
+	if want := ssh.FingerprintSHA256(hostKey); got[0].Fingerprint != want {
+		t.Errorf("Fingerprint = %q, want %q", got[0].Fingerprint, want)
+	}
+}
+
+func TestScannerPortSweepReportsSSHPorts(t *testing.T) {
+	addr, _ := startTestServer(t, "toor")
+	host, portStr, _ := net.SplitHostPort(addr)
+	sshPort, _ := strconv.Atoi(portStr)
+
+	// A closed port next to the SSH one
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedPort := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	cfg := &Config{Workers: 4, Port: 22, User: "root", Password: "wrong", Timeout: time.Second,
+		Ports: []int{sshPort, closedPort}, Precheck: 200 * time.Millisecond}
+	targets, _, err := rangeTargets([]string{host}, cfg.Ports, cfg.bufferSize())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewScanner(cfg)
+	results := make(chan Result)
+	go s.Run(context.Background(), targets, results)
+
+	byPort := make(map[int]Result)
+	for res := range results {
+		byPort[res.Port] = res
+	}
+	if res := byPort[sshPort]; res.Fingerprint == "" || res.Category != failAuth {
+		t.Errorf("SSH port result = %+v, want a host key and an auth failure", res)
+	}
+	if res := byPort[closedPort]; !res.precheckFailed {
+		t.Errorf("closed port result = %+v, want a failed pre-check", res)
+	}
+	if stats := s.Stats(); stats.SSH != 1 || stats.Closed != 1 {
+		t.Errorf("Stats() = %+v, want SSH=1 Closed=1", stats)
+	}
+}
+
+func TestScannerRampStartsWithOneWorker(t *testing.T) {
+	// A ramp far longer than the test never releases a second slot
+	cfg := &Config{Workers: 8, Port: 22, Timeout: time.Second, Ramp: time.Hour}
+	s := NewScanner(cfg)
+
+	var inFlight, peak atomic.Int32
+	s.connect = func(addr string, config *ssh.ClientConfig) error {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		return nil
+	}
+
+	runScanner(s, "10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4")
+	if got := peak.Load(); got != 1 {
+		t.Errorf("peak concurrency = %d, want 1 at the start of the ramp", got)
+	}
+}
+
+func TestScannerTinyRamp(t *testing.T) {
+	// Shorter than one nanosecond per worker, which NewTicker refuses
+	cfg := &Config{Workers: 100, Port: 22, Timeout: time.Second, Ramp: time.Nanosecond}
+	s := NewScanner(cfg)
+	s.connect = func(addr string, config *ssh.ClientConfig) error {
+		return nil
+	}
+
+	if got := runScanner(s, "10.0.0.1", "10.0.0.2", "10.0.0.3"); len(got) != 3 {
+		t.Errorf("Run() produced %d results, want 3", len(got))
+	}
+}
+
+func TestScannerRetryQueue(t *testing.T) {
+	cfg := &Config{Workers: 2, Port: 22, Timeout: time.Second, Retries: 2}
+	s := NewScanner(cfg)
+
+	// 10.0.0.1 resets twice before accepting, 10.0.0.2 resets forever
+	// and 10.0.0.3 is refused, which is never retried.
+	var mu sync.Mutex
+	calls := make(map[string]int)
+	s.connect = func(addr string, config *ssh.ClientConfig) error {
+		mu.Lock()
+		calls[addr]++
+		n := calls[addr]
+		mu.Unlock()
+
+		switch {
+		case strings.HasPrefix(addr, "10.0.0.1:") && n > 2:
+			return nil
+		case strings.HasPrefix(addr, "10.0.0.3:"):
+			return syscall.ECONNREFUSED
+		default:
+			return syscall.ECONNRESET
+		}
+	}
+
+	got := runScanner(s, "10.0.0.1", "10.0.0.2", "10.0.0.3")
+	if len(got) != 3 {
+		t.Fatalf("Run() produced %d results, want one per host", len(got))
+	}
+	if calls["10.0.0.1:22"] != 3 || calls["10.0.0.2:22"] != 3 || calls["10.0.0.3:22"] != 1 {
+		t.Errorf("attempts per host = %v", calls)
+	}
+
+	stats := s.Stats()
+	want := Stats{Processed: 3, Found: 1, Failed: 2, Retries: 4, RetrySuccess: 1}
+	if stats != want {
+		t.Errorf("Stats() = %+v, want %+v", stats, want)
+	}
+}
+
+func TestScannerMaxFoundPerSubnet(t *testing.T) {
+	cfg := &Config{Workers: 1, Port: 22, Timeout: time.Second, MaxFoundPerSubnet: 2}
+	s := NewScanner(cfg)
+	var calls atomic.Int32
+	s.connect = func(addr string, config *ssh.ClientConfig) error {
+		calls.Add(1)
+		return nil
+	}
+
+	got := runScanner(s, "10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4", "10.0.1.1")
+	if len(got) != 5 {
+		t.Fatalf("Run() produced %d results, want one per target", len(got))
+	}
+	if n := calls.Load(); n != 3 {
+		t.Errorf("%d attempts, want 2 in 10.0.0.0/24 and 1 in 10.0.1.0/24", n)
+	}
+	stats := s.Stats()
+	if stats.Found != 3 || stats.Saturated != 2 || s.FailureCounts()[failSampled] != 2 {
+		t.Errorf("Stats() = %+v, want 3 found and 2 sampled out", stats)
+	}
+}
+
+func TestScannerSkipsKnownFound(t *testing.T) {
+	cfg := &Config{Workers: 1, Port: 22, Timeout: time.Second}
+	s := NewScanner(cfg)
+	s.known = map[string]bool{"10.0.0.1": true, "10.0.0.2:2222": true}
+	var attempted []string
+	s.connect = func(addr string, config *ssh.ClientConfig) error {
+		attempted = append(attempted, addr)
+		return nil
+	}
+
+	in := make(chan Target, 3)
+	in <- Target{Host: "10.0.0.1"}
+	in <- Target{Host: "10.0.0.2", Port: 2222}
+	in <- Target{Host: "10.0.0.2"} // the same host on another port is new
+	close(in)
+	out := make(chan Result)
+	go s.Run(context.Background(), in, out)
+	for range out {
+	}
+
+	if len(attempted) != 1 || attempted[0] != "10.0.0.2:22" {
+		t.Errorf("attempted %v, want only 10.0.0.2:22", attempted)
+	}
+	if stats := s.Stats(); stats.Known != 2 || s.FailureCounts()[failKnown] != 2 {
+		t.Errorf("Stats() = %+v, want 2 known", stats)
+	}
+}
+
+func TestScannerRetryOn(t *testing.T) {
+	for _, tt := range []struct {
+		retryOn []failureCategory
+		want    int
+	}{
+		{nil, 1},
+		{[]failureCategory{failTimeout, failUnreachable}, 3},
+	} {
+		cfg := &Config{Workers: 1, Port: 22, Timeout: time.Second, Retries: 2, RetryOn: tt.retryOn}
+		s := NewScanner(cfg)
+		var calls atomic.Int32
+		s.connect = func(addr string, config *ssh.ClientConfig) error {
+			calls.Add(1)
+			return &net.OpError{Op: "dial", Net: "tcp", Err: syscall.EHOSTUNREACH}
+		}
+		runScanner(s, "10.0.0.1")
+		if n := calls.Load(); int(n) != tt.want {
+			t.Errorf("-retry-on %v: %d attempts on an unreachable host, want %d", tt.retryOn, n, tt.want)
+		}
+	}
+
+	if _, err := parseRetryOn("timeout,refused"); err == nil {
+		t.Error("parseRetryOn() accepted refused")
+	}
+}
+
+func TestScannerPrecheckSkipsClosedPorts(t *testing.T) {
+	// Grab a free port and close it again so nothing is listening
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	cfg := &Config{Workers: 1, Port: port, Timeout: time.Second, Precheck: 200 * time.Millisecond}
+	s := NewScanner(cfg)
+
+	var calls atomic.Int32
+	s.connect = func(addr string, config *ssh.ClientConfig) error {
+		calls.Add(1)
+		return nil
+	}
+
+	got := runScanner(s, "127.0.0.1")
+	if len(got) != 1 || got[0].Success {
+		t.Fatalf("Run() = %+v, want one failed result", got)
+	}
+	if calls.Load() != 0 {
+		t.Errorf("SSH connect was attempted on a closed port")
+	}
+	if stats := s.Stats(); stats.Closed != 1 {
+		t.Errorf("Stats().Closed = %d, want 1", stats.Closed)
+	}
+}
+
+func TestScannerPerSubnetCap(t *testing.T) {
+	cfg := &Config{Workers: 16, Port: 22, Timeout: time.Second, PerSubnet: 2}
+	s := NewScanner(cfg)
+
+	var (
+		mu       sync.Mutex
+		inFlight = make(map[string]int)
+		peak     = make(map[string]int)
+	)
+	s.connect = func(addr string, config *ssh.ClientConfig) error {
+		host, _, _ := net.SplitHostPort(addr)
+		key := subnetKey(host)
+
+		mu.Lock()
+		inFlight[key]++
+		peak[key] = max(peak[key], inFlight[key])
+		mu.Unlock()
+
+		time.Sleep(5 * time.Millisecond)
+
+		mu.Lock()
+		inFlight[key]--
+		mu.Unlock()
+		return nil
+	}
+
+	var ips []string
+	for i := 1; i <= 6; i++ {
+		ips = append(ips, "10.0.0."+strconv.Itoa(i), "10.0.1."+strconv.Itoa(i))
+	}
+	runScanner(s, ips...)
+
+	for subnet, n := range peak {
+		if n > 2 {
+			t.Errorf("subnet %s peaked at %d concurrent attempts, want at most 2", subnet, n)
+		}
+	}
+}
+
+func TestScannerPerSubnetRunsSubnetsInParallel(t *testing.T) {
+	cfg := &Config{Workers: 4, Port: 22, Timeout: time.Second, PerSubnet: 1}
+	s := NewScanner(cfg)
+
+	var inFlight, peak atomic.Int32
+	s.connect = func(addr string, config *ssh.ClientConfig) error {
+		n := inFlight.Add(1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		time.Sleep(20 * time.Millisecond)
+		inFlight.Add(-1)
+		return nil
+	}
+
+	// In order, as a range scan produces them: the second /24 must not wait
+	// behind the whole first one
+	var ips []string
+	for _, subnet := range []string{"10.0.0.", "10.0.1."} {
+		for i := 1; i <= 3; i++ {
+			ips = append(ips, subnet+strconv.Itoa(i))
+		}
+	}
+	if got := runScanner(s, ips...); len(got) != len(ips) {
+		t.Fatalf("Run() produced %d results, want %d", len(got), len(ips))
+	}
+	if got := peak.Load(); got != 2 {
+		t.Errorf("peak concurrency = %d, want 2: one per subnet", got)
+	}
+}
+
+func TestScannerPerSubnetStopsOnCancel(t *testing.T) {
+	cfg := &Config{Workers: 2, Port: 22, Timeout: time.Second, PerSubnet: 1}
+	s := NewScanner(cfg)
+	ctx, cancel := context.WithCancel(context.Background())
+	s.connect = func(addr string, config *ssh.ClientConfig) error {
+		cancel()
+		return nil
+	}
+
+	in := make(chan Target, 10)
+	for i := 1; i <= 10; i++ {
+		in <- Target{Host: "10.0.0." + strconv.Itoa(i)}
+	}
+	close(in)
+	out := make(chan Result, 10)
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx, in, out)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run() did not return after cancel")
+	}
+	if n := len(out); n > 1 {
+		t.Errorf("%d targets were attempted after cancel, want at most the first", n)
+	}
+}
+
+func TestScannerSharedLimiter(t *testing.T) {
+	shared := NewLimiter(3)
+	var inFlight, peak atomic.Int32
+	connect := func(addr string, config *ssh.ClientConfig) error {
+		n := inFlight.Add(1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		time.Sleep(5 * time.Millisecond)
+		inFlight.Add(-1)
+		return nil
+	}
+
+	var ips []string
+	for i := 1; i <= 12; i++ {
+		ips = append(ips, "10.0.0."+strconv.Itoa(i))
+	}
+	var wg sync.WaitGroup
+	for range 3 {
+		cfg := &Config{Workers: 2, Port: 22, Timeout: time.Second, Limiter: shared}
+		s := NewScanner(cfg)
+		s.connect = connect
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if got := runScanner(s, ips...); len(got) != len(ips) {
+				t.Errorf("Run() = %d results, want %d", len(got), len(ips))
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Three scanners of 2 workers each would reach 6 on their own
+	if n := peak.Load(); n > 3 {
+		t.Errorf("shared limiter of 3 peaked at %d concurrent attempts", n)
+	}
+	if n := shared.InUse(); n != 0 {
+		t.Errorf("InUse() = %d after every scan finished, want 0", n)
+	}
+}
+
+func TestScannerClientVersion(t *testing.T) {
+	versions := make(chan string, 1)
+	addr, _ := startSSHServer(t, &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+			versions <- string(conn.ClientVersion())
+			return nil, nil
+		},
+	})
+	host, portStr, _ := net.SplitHostPort(addr)
+	port, _ := strconv.Atoi(portStr)
+
+	cfg := &Config{Workers: 1, Port: port, User: "root", Timeout: time.Second,
+		ClientVersion: "SSH-2.0-OpenSSH_9.6", KeepAlive: -1}
+	if got := runScanner(NewScanner(cfg), host); len(got) != 1 || !got[0].Success {
+		t.Fatalf("Run() = %+v, want one success", got)
+	}
+	if got := <-versions; got != cfg.ClientVersion {
+		t.Errorf("server saw client version %q, want %q", got, cfg.ClientVersion)
+	}
+}
+
+func TestScannerMaxAttempts(t *testing.T) {
+	addr, _ := startTestServer(t, "toor")
+	host, portStr, _ := net.SplitHostPort(addr)
+	port, _ := strconv.Atoi(portStr)
+
+	cfg := &Config{Workers: 1, Port: port, User: "root", Password: "wrong", Timeout: time.Second, MaxAttempts: 3}
+	s := NewScanner(cfg)
+	in := make(chan Target, 10)
+	for range 10 {
+		in <- Target{Host: host}
+	}
+	close(in)
+	out := make(chan Result)
+	go s.Run(context.Background(), in, out)
+
+	var got []Result
+	for res := range out {
+		got = append(got, res)
+	}
+	if len(got) != 3 {
+		t.Errorf("Run() reported %d results, want the 3 attempts under the cap", len(got))
+	}
+	for _, res := range got {
+		if res.Category != failAuth {
+			t.Errorf("result %+v, want a rejected login", res)
+		}
+	}
+	if !s.AttemptsCapped() || s.Stats().Processed != 3 {
+		t.Errorf("AttemptsCapped() = %v, Processed = %d; want true, 3", s.AttemptsCapped(), s.Stats().Processed)
+	}
+}
+
+func TestScannerLegacyFallback(t *testing.T) {
+	config := &ssh.ServerConfig{
+		Config: ssh.Config{Ciphers: []string{"aes128-cbc"}},
+		PasswordCallback: func(conn ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+			return &ssh.Permissions{}, nil
+		},
+	}
+	addr, _ := startSSHServer(t, config)
+	host, portStr, _ := net.SplitHostPort(addr)
+	port, _ := strconv.Atoi(portStr)
+
+	for _, fallback := range []bool{false, true} {
+		cfg := &Config{Workers: 1, Port: port, User: "root", Password: "toor", Timeout: time.Second, LegacyFallback: fallback}
+		got := runScanner(NewScanner(cfg), host)
+		if len(got) != 1 || got[0].Success != fallback || got[0].Legacy != fallback {
+			t.Errorf("-legacy-fallback=%v: Run() = %+v, want success and legacy %v", fallback, got, fallback)
+		}
+		if !fallback && !isNoCommonAlgorithm(got[0].err) {
+			t.Errorf("without -legacy-fallback: error %v, want no common algorithm", got[0].err)
+		}
+	}
+}
+
+func TestScannerAuthBanner(t *testing.T) {
+	const warning = "Authorized use only. Activity is logged.\n"
+	addr, _ := startSSHServer(t, &ssh.ServerConfig{
+		BannerCallback: func(conn ssh.ConnMetadata) string { return warning },
+		PasswordCallback: func(conn ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+			return nil, errors.New("denied")
+		},
+	})
+	host, portStr, _ := net.SplitHostPort(addr)
+	port, _ := strconv.Atoi(portStr)
+
+	for _, record := range []bool{true, false} {
+		cfg := &Config{Workers: 1, Port: port, User: "root", Password: "toor", Timeout: time.Second, AuthBanner: record}
+		got := runScanner(NewScanner(cfg), host)
+		if len(got) != 1 || got[0].Category != failAuth {
+			t.Fatalf("-auth-banner=%v: Run() = %+v, want one rejected login", record, got)
+		}
+		want := ""
+		if record {
+			want = warning
+		}
+		if got[0].AuthBanner != want {
+			t.Errorf("-auth-banner=%v: AuthBanner = %q, want %q", record, got[0].AuthBanner, want)
+		}
+	}
+}
+
+// BenchmarkScannerRun measures how many targets per second Run gets through
+// when every attempt takes a fixed time, so that the worker count, not the
+// network, is the limit. Throughput well below workers/latency points at
+// overhead in the dispatch machinery.
+func BenchmarkScannerRun(b *testing.B) {
+	const latency = time.Millisecond
+	for _, workers := range []int{1, 10, 100, 1000} {
+		b.Run("workers="+strconv.Itoa(workers), func(b *testing.B) {
+			s := NewScanner(&Config{Workers: workers, Port: 22, User: "root", Password: "toor", Timeout: time.Second})
+			s.connect = func(addr string, config *ssh.ClientConfig) error {
+				time.Sleep(latency)
+				return errors.New("ssh: handshake failed: ssh: unable to authenticate")
+			}
+
+			targets := make(chan Target, workers)
+			go func() {
+				defer close(targets)
+				for i := range b.N {
+					targets <- Target{Host: "10.0.0." + strconv.Itoa(i%256)}
+				}
+			}()
+			results := make(chan Result, workers)
+
+			b.ResetTimer()
+			go s.Run(context.Background(), targets, results)
+			for range results {
+			}
+			b.StopTimer()
+
+			b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "targets/s")
+			b.ReportMetric(float64(workers)/latency.Seconds(), "ideal/s")
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Access levels reported by -verify-shell
+const (
+	accessShell    = "shell"     // the command ran and printed a uid
+	accessAuthOnly = "auth-only" // logged in, but no usable command execution
+)
+
+// shellCommand is run by -verify-shell. Its "uid=" output is distinctive
+// enough that a forced command or a menu printing something else is not
+// mistaken for a shell.
+const shellCommand = "id"
+
+// connectShell is the -verify-shell variant of tryConnectSSH: after the
+// login it opens a session and runs shellCommand on the same connection.
+func (s *Scanner) connectShell(addr string, config *ssh.ClientConfig) (string, error) {
+	conn, err := s.dial(addr, config.Timeout)
+	if err != nil {
+		return "", err
+	}
+	defer context.AfterFunc(s.ctx, func() { conn.Close() })()
+	if s.cfg.BannerTimeout > 0 {
+		conn = newBannerConn(conn, s.cfg.BannerTimeout)
+	}
+
+	expired := s.authDeadline(conn)
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	timedOut := expired()
+	if err != nil {
+		conn.Close()
+		if s.ctx.Err() != nil {
+			return "", s.ctx.Err()
+		}
+		if timedOut {
+			return "", errAuthTimeout
+		}
+		return "", err
+	}
+	client := ssh.NewClient(c, chans, reqs)
+	defer client.Close()
+	return shellAccess(client, config.Timeout), nil
+}
+
+// verifyShell logs in again with password to learn the access level, for
+// logins found by trying several passwords on one connection.
+func (s *Scanner) verifyShell(addr string, config *ssh.ClientConfig, password string) string {
+	verify := *config
+	verify.Auth = []ssh.AuthMethod{ssh.Password(password)}
+	access, err := s.connectShell(addr, &verify)
+	if err != nil {
+		return ""
+	}
+	return access
+}
+
+// shellAccess runs shellCommand on client. A rejected session, a failing
+// command or output without a uid all mean the credentials are good for
+// authentication only. The command gets timeout to finish, so a forced
+// interactive command cannot hold the worker.
+func shellAccess(client *ssh.Client, timeout time.Duration) string {
+	session, err := client.NewSession()
+	if err != nil {
+		return accessAuthOnly
+	}
+	defer session.Close()
+
+	timer := time.AfterFunc(timeout, func() { client.Close() })
+	defer timer.Stop()
+	out, err := session.Output(shellCommand)
+	if err != nil || !bytes.Contains(out, []byte("uid=")) {
+		return accessAuthOnly
+	}
+	return accessShell
+}
+
+// connectVerified connects through s.connect, or with -verify-shell through
+// connectShell, recording the access level in res.
+func (s *Scanner) connectVerified(addr string, config *ssh.ClientConfig, res *Result) error {
+	if !s.cfg.VerifyShell {
+		return s.connect(addr, config)
+	}
+	access, err := s.connectShell(addr, config)
+	res.Access = access
+	return err
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// startShellServer runs an SSH server accepting root/toor that answers every
+// exec request with output, as a forced command would.
+func startShellServer(t *testing.T, output string) string {
+	t.Helper()
+
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+			return &ssh.Permissions{}, nil
+		},
+	}
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.AddHostKey(signer)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, chans, reqs, err := ssh.NewServerConn(conn, config)
+				if err != nil {
+					return
+				}
+				go ssh.DiscardRequests(reqs)
+				for newCh := range chans {
+					ch, requests, err := newCh.Accept()
+					if err != nil {
+						return
+					}
+					for req := range requests {
+						if req.Type != "exec" {
+							req.Reply(false, nil)
+							continue
+						}
+						req.Reply(true, nil)
+						ch.Write([]byte(output))
+						ch.SendRequest("exit-status", false, binary.BigEndian.AppendUint32(nil, 0))
+						ch.Close()
+					}
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestScannerVerifyShell(t *testing.T) {
+	shell := startShellServer(t, "uid=0(root) gid=0(root) groups=0(root)\n")
+	forced := startShellServer(t, "This account is restricted to file transfers.\n")
+	noChannels, _ := startTestServer(t, "toor")
+
+	for _, tt := range []struct {
+		addr string
+		want string
+	}{
+		{shell, accessShell},
+		{forced, accessAuthOnly},
+		{noChannels, accessAuthOnly},
+	} {
+		host, portStr, _ := net.SplitHostPort(tt.addr)
+		port, _ := strconv.Atoi(portStr)
+
+		cfg := &Config{Workers: 1, Port: port, User: "root", Password: "toor", Timeout: time.Second, VerifyShell: true}
+		got := runScanner(NewScanner(cfg), host)
+
+		if len(got) != 1 || !got[0].Success || got[0].Access != tt.want {
+			t.Errorf("Run() against %s = %+v, want a login with access %q", tt.addr, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// First two octets of the numeric host shortcuts, set by -shortcut-base
+var shortcutBase = [2]int{192, 168}
+
+// parseShortcutBase accepts two octets such as "10.20".
+func parseShortcutBase(s string) ([2]int, error) {
+	a, b, ok := strings.Cut(s, ".")
+	x, errX := parseOctet(a)
+	y, errY := parseOctet(b)
+	if !ok || errX != nil || errY != nil {
+		return [2]int{}, fmt.Errorf("%q is not two octets like 10.20", s)
+	}
+	return [2]int{x, y}, nil
+}
+
+// shortcutCIDR expands the numeric shortcuts relative to shortcutBase:
+// "3" is the /24 base.3.0/24 and "3.5" the single address base.3.5. Any
+// other input is returned unchanged.
+func shortcutCIDR(input string) string {
+	third, fourth, hasHost := strings.Cut(input, ".")
+	c, err := parseOctet(third)
+	if err != nil {
+		return input
+	}
+	if !hasHost {
+		return fmt.Sprintf("%d.%d.%d.0/24", shortcutBase[0], shortcutBase[1], c)
+	}
+	d, err := parseOctet(fourth)
+	if err != nil {
+		return input
+	}
+	return fmt.Sprintf("%d.%d.%d.%d/32", shortcutBase[0], shortcutBase[1], c, d)
+}
+
+// expandShortcut splits the range shortcuts into specs parseInput accepts:
+// "3-5" into the /24s 3, 4 and 5, and "3.10-20" into the addresses 3.10 to
+// 3.20. Other specs are returned as they are.
+func expandShortcut(spec string) []string {
+	prefix, last, isRange := strings.Cut(spec, "-")
+	if !isRange {
+		return []string{spec}
+	}
+	third, fourth, hasHost := strings.Cut(prefix, ".")
+
+	first := third
+	if hasHost {
+		first = fourth
+		if _, err := parseOctet(third); err != nil {
+			return []string{spec}
+		}
+	}
+	lo, errLo := parseOctet(first)
+	hi, errHi := parseOctet(last)
+	if errLo != nil || errHi != nil || hi < lo {
+		return []string{spec}
+	}
+
+	specs := make([]string, 0, hi-lo+1)
+	for n := lo; n <= hi; n++ {
+		if hasHost {
+			specs = append(specs, third+"."+strconv.Itoa(n))
+		} else {
+			specs = append(specs, strconv.Itoa(n))
+		}
+	}
+	return specs
+}
+
+func expandShortcuts(specs []string) []string {
+	var out []string
+	for _, spec := range specs {
+		out = append(out, expandShortcut(spec)...)
+	}
+	return out
+}
+
+func parseOctet(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 || n > 255 || s != strconv.Itoa(n) {
+		return 0, fmt.Errorf("invalid octet %q", s)
+	}
+	return n, nil
+}
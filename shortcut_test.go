@@ -0,0 +1,49 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestShortcuts(t *testing.T) {
+	defer func(base [2]int) { shortcutBase = base }(shortcutBase)
+
+	tests := []struct {
+		base   [2]int
+		input  string
+		expand []string
+		cidr   string
+	}{
+		{[2]int{192, 168}, "3", []string{"3"}, "192.168.3.0/24"},
+		{[2]int{192, 168}, "3.5", []string{"3.5"}, "192.168.3.5/32"},
+		{[2]int{10, 20}, "3", []string{"3"}, "10.20.3.0/24"},
+		{[2]int{192, 168}, "3-5", []string{"3", "4", "5"}, ""},
+		{[2]int{192, 168}, "3.10-12", []string{"3.10", "3.11", "3.12"}, ""},
+		{[2]int{192, 168}, "10.0.0.0/8", []string{"10.0.0.0/8"}, "10.0.0.0/8"},
+		{[2]int{192, 168}, "5-3", []string{"5-3"}, ""},
+		{[2]int{192, 168}, "256", []string{"256"}, "256"},
+	}
+	for _, tt := range tests {
+		shortcutBase = tt.base
+		if got := expandShortcut(tt.input); !reflect.DeepEqual(got, tt.expand) {
+			t.Errorf("expandShortcut(%q) = %q, want %q", tt.input, got, tt.expand)
+		}
+		if tt.cidr == "" {
+			continue
+		}
+		if got := shortcutCIDR(tt.input); got != tt.cidr {
+			t.Errorf("base %v: shortcutCIDR(%q) = %q, want %q", tt.base, tt.input, got, tt.cidr)
+		}
+	}
+}
+
+func TestParseShortcutBase(t *testing.T) {
+	if got, err := parseShortcutBase("10.20"); err != nil || got != [2]int{10, 20} {
+		t.Errorf("parseShortcutBase(10.20) = %v, %v", got, err)
+	}
+	for _, bad := range []string{"", "10", "10.20.30", "256.1", "a.b", "10.-1"} {
+		if _, err := parseShortcutBase(bad); err == nil {
+			t.Errorf("parseShortcutBase(%q) expected error", bad)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// printConfig writes the settings a scan resolved to, for -show-config: the
+// values after presets, environment variables and worker clamping, so a log
+// records what actually ran. Passwords are shown only as their length and a
+// short hash, enough to tell two runs apart without leaking them.
+func printConfig(w io.Writer, cfg *Config) {
+	fmt.Fprintf(w, "%s[i] Config:%s\n", ColorBlue, ColorReset)
+	for _, line := range configLines(cfg) {
+		fmt.Fprintf(w, "    %s\n", line)
+	}
+}
+
+func configLines(cfg *Config) []string {
+	lines := []string{"targets: " + cfg.scope}
+
+	workers := fmt.Sprintf("workers: %d", cfg.Workers)
+	if cfg.PerSubnet > 0 {
+		workers += fmt.Sprintf(", at most %d per subnet", cfg.PerSubnet)
+	}
+	if cfg.Ramp > 0 {
+		workers += fmt.Sprintf(", ramped up over %v", cfg.Ramp)
+	}
+	lines = append(lines, workers)
+
+	timeout := fmt.Sprintf("timeout: %v, %d retries", cfg.Timeout, cfg.Retries)
+	if cfg.FastTimeout > 0 {
+		timeout += fmt.Sprintf(", first pass at %v", cfg.FastTimeout)
+	}
+	if cfg.Precheck > 0 {
+		timeout += fmt.Sprintf(", pre-check %v", cfg.Precheck)
+	}
+	lines = append(lines, timeout)
+
+	ports := portsLabel(cfg)
+	if cfg.InputFile != "" {
+		ports += " unless the target file gives one"
+	}
+	lines = append(lines, "ports: "+ports)
+
+	creds := fmt.Sprintf("user %q", cfg.User)
+	if cfg.Signer != nil {
+		creds += ", key " + cfg.KeyFile
+	} else {
+		creds += ", password " + redactSecret(cfg.Password)
+	}
+	lines = append(lines, "credentials: "+creds)
+	if sources := passwordSources(cfg); len(sources) > 0 {
+		more := strings.Join(sources, ", ")
+		if cfg.ShuffleCreds {
+			more += fmt.Sprintf(", shuffled with seed %d", cfg.Seed)
+		}
+		lines = append(lines, "more passwords: "+more+", policy "+cfg.CredPolicy)
+	}
+
+	route := routeLabel(cfg.HTTPProxy)
+	if len(cfg.SourceIPs) > 0 {
+		route += fmt.Sprintf(" from %d source addresses", len(cfg.SourceIPs))
+	}
+	if len(cfg.Knock) > 0 {
+		route += ", knocking " + cfg.Knock.String()
+	}
+	lines = append(lines, "route: "+route)
+
+	var limits []string
+	if cfg.MaxTime > 0 {
+		limits = append(limits, fmt.Sprintf("-max-time %v", cfg.MaxTime))
+	}
+	if cfg.MaxAttempts > 0 {
+		limits = append(limits, fmt.Sprintf("-max-attempts %d", cfg.MaxAttempts))
+	}
+	if cfg.Breaker > 0 {
+		limits = append(limits, fmt.Sprintf("-breaker %g of %d for %v", cfg.Breaker, cfg.BreakerWindow, cfg.BreakerCooldown))
+	}
+	if cfg.Window != nil {
+		limits = append(limits, "-window "+cfg.Window.String())
+	}
+	if len(limits) > 0 {
+		lines = append(lines, "limits: "+strings.Join(limits, ", "))
+	}
+
+	var outputs []string
+	for _, out := range []struct{ flag, path string }{
+		{"-o", cfg.OutputFile},
+		{"-json", cfg.JSONFile},
+		{"-reachable-file", cfg.ReachableFile},
+		{"-state", cfg.StateFile},
+		{"-syslog", cfg.Syslog},
+	} {
+		if out.path != "" {
+			outputs = append(outputs, out.flag+" "+out.path)
+		}
+	}
+	if len(outputs) > 0 {
+		lines = append(lines, "output: "+strings.Join(outputs, ", "))
+	}
+	return lines
+}
+
+// passwordSources names where the passwords beyond -p come from.
+func passwordSources(cfg *Config) []string {
+	var sources []string
+	if len(cfg.Passwords) > 0 {
+		sources = append(sources, fmt.Sprintf("%d from -passwords", len(cfg.Passwords)))
+	}
+	if cfg.HeuristicCreds {
+		sources = append(sources, "-heuristic-creds")
+	}
+	if cfg.Mangle != 0 {
+		sources = append(sources, "-mangle")
+	}
+	if cfg.LearnCreds {
+		sources = append(sources, "-learn-creds")
+	}
+	return sources
+}
+
+// redactSecret describes a password without revealing it.
+func redactSecret(secret string) string {
+	if secret == "" {
+		return "(empty)"
+	}
+	sum := sha256.Sum256([]byte(secret))
+	return fmt.Sprintf("(%d chars, sha256 %s)", len(secret), hex.EncodeToString(sum[:4]))
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfigLinesRedactPasswords(t *testing.T) {
+	cfg := &Config{
+		User: "root", Password: "hunter2", Passwords: []string{"s3cret", "letmein"},
+		Workers: 50, Timeout: 5 * time.Second, Port: 22, CredPolicy: credExhaustive,
+		OutputFile: "found.txt", scope: "10.0.0.0/24 on port 22",
+	}
+	got := strings.Join(configLines(cfg), "\n")
+	for _, secret := range []string{"hunter2", "s3cret", "letmein"} {
+		if strings.Contains(got, secret) {
+			t.Errorf("configLines() shows password %q:\n%s", secret, got)
+		}
+	}
+	for _, want := range []string{
+		"targets: 10.0.0.0/24 on port 22",
+		"workers: 50",
+		`credentials: user "root", password (7 chars, sha256 f52fbd32)`,
+		"more passwords: 2 from -passwords",
+		"output: -o found.txt",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("configLines() lacks %q:\n%s", want, got)
+		}
+	}
+
+	if redactSecret("") != "(empty)" {
+		t.Errorf("redactSecret(\"\") = %q", redactSecret(""))
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"hash/fnv"
+	"iter"
+	"math/rand/v2"
+	"slices"
+)
+
+// shuffledFor yields passwords in an order of their own for host, for
+// -shuffle-creds. The order derives from seed and the host alone, so the
+// same -seed reproduces it while neighbouring hosts see different sequences.
+func shuffledFor(passwords iter.Seq[string], host string, seed uint64) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		list := slices.Collect(passwords)
+		h := fnv.New64a()
+		h.Write([]byte(host))
+		r := rand.New(rand.NewPCG(seed, h.Sum64()))
+		r.Shuffle(len(list), func(i, j int) {
+			list[i], list[j] = list[j], list[i]
+		})
+		for _, password := range list {
+			if !yield(password) {
+				return
+			}
+		}
+	}
+}
+
+// randomSeed picks the -seed of a -shuffle-creds run when none was given. It
+// is printed at the start of the scan so the run can be repeated.
+func randomSeed() uint64 {
+	return rand.Uint64()
+}
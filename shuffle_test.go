@@ -0,0 +1,30 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestShuffledFor(t *testing.T) {
+	passwords := slices.Values([]string{"a", "b", "c", "d", "e", "f", "g", "h"})
+	order := func(host string, seed uint64) []string {
+		return slices.Collect(shuffledFor(passwords, host, seed))
+	}
+
+	first := order("10.0.0.1", 42)
+	if !slices.Equal(first, order("10.0.0.1", 42)) {
+		t.Error("same host and seed gave different orders")
+	}
+	if sorted := slices.Sorted(slices.Values(first)); !slices.Equal(sorted, slices.Collect(passwords)) {
+		t.Errorf("shuffledFor() = %q, want a permutation of the passwords", first)
+	}
+
+	// With 8! orders a collision on every one of these is practically impossible
+	differs := func(other []string) bool { return !slices.Equal(first, other) }
+	if !differs(order("10.0.0.2", 42)) && !differs(order("10.0.0.3", 42)) {
+		t.Error("neighbouring hosts got the same order")
+	}
+	if !differs(order("10.0.0.1", 43)) && !differs(order("10.0.0.1", 44)) {
+		t.Error("another -seed gave the same order")
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// parseSourceIPs parses the comma-separated -source-ips list. Every address
+// must be assigned to one of the interfaces in local, since binding to any
+// other fails on every dial.
+func parseSourceIPs(list string, local []net.Addr) ([]net.IP, error) {
+	var ips []net.IP
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		ip := net.ParseIP(item)
+		if ip == nil {
+			return nil, fmt.Errorf("%q is not an IP address", item)
+		}
+		if !isLocalIP(ip, local) {
+			return nil, fmt.Errorf("%s is not assigned to any local interface", ip)
+		}
+		ips = append(ips, ip)
+	}
+	return ips, nil
+}
+
+func isLocalIP(ip net.IP, local []net.Addr) bool {
+	for _, addr := range local {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// sourceAddr picks the next -source-ips address, round-robin, for a dial to
+// host. Only addresses of the host's family are considered; nil leaves the
+// choice to the system.
+func (s *Scanner) sourceAddr(host string) *net.TCPAddr {
+	ips := s.cfg.SourceIPs
+	if len(ips) == 0 {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		v4 := ip.To4() != nil
+		var family []net.IP
+		for _, src := range ips {
+			if (src.To4() != nil) == v4 {
+				family = append(family, src)
+			}
+		}
+		ips = family
+	}
+	if len(ips) == 0 {
+		return nil
+	}
+	n := s.sourceNext.Add(1) - 1
+	return &net.TCPAddr{IP: ips[n%uint32(len(ips))]}
+}
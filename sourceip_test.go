@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestParseSourceIPs(t *testing.T) {
+	local := []net.Addr{
+		&net.IPNet{IP: net.ParseIP("10.0.0.5"), Mask: net.CIDRMask(24, 32)},
+		&net.IPNet{IP: net.ParseIP("10.0.0.6"), Mask: net.CIDRMask(24, 32)},
+		&net.IPNet{IP: net.ParseIP("fd00::5"), Mask: net.CIDRMask(64, 128)},
+	}
+
+	got, err := parseSourceIPs("10.0.0.5, fd00::5", local)
+	if err != nil || len(got) != 2 || !got[1].Equal(net.ParseIP("fd00::5")) {
+		t.Errorf("parseSourceIPs() = %v, %v", got, err)
+	}
+	for _, bad := range []string{"", "10.0.0.7", "10.0.0.5,eth0", "10.0.0.0/24"} {
+		if _, err := parseSourceIPs(bad, local); err == nil {
+			t.Errorf("parseSourceIPs(%q) expected error", bad)
+		}
+	}
+}
+
+func TestSourceAddrRoundRobin(t *testing.T) {
+	s := NewScanner(&Config{SourceIPs: []net.IP{
+		net.ParseIP("10.0.0.5"), net.ParseIP("fd00::5"), net.ParseIP("10.0.0.6"),
+	}})
+
+	var got []string
+	for range 3 {
+		got = append(got, s.sourceAddr("192.168.1.1").IP.String())
+	}
+	if got[0] != "10.0.0.5" || got[1] != "10.0.0.6" || got[2] != "10.0.0.5" {
+		t.Errorf("IPv4 sources = %v, want 10.0.0.5 and 10.0.0.6 alternating", got)
+	}
+	if src := s.sourceAddr("fd00::9"); src == nil || src.IP.String() != "fd00::5" {
+		t.Errorf("IPv6 source = %v, want fd00::5", src)
+	}
+	if src := NewScanner(&Config{}).sourceAddr("192.168.1.1"); src != nil {
+		t.Errorf("sourceAddr() without -source-ips = %v, want nil", src)
+	}
+}
+
+func TestDialUsesSourceIPs(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	// All of 127.0.0.0/8 is on the loopback interface on Linux
+	s := NewScanner(&Config{SourceIPs: []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("127.0.0.2")}})
+	for _, want := range []string{"127.0.0.1", "127.0.0.2"} {
+		conn, err := s.dial(ln.Addr().String(), time.Second)
+		if err != nil {
+			t.Skipf("can't bind %s here: %v", want, err)
+		}
+		peer, err := ln.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := peer.RemoteAddr().(*net.TCPAddr).IP.String(); got != want {
+			t.Errorf("connection came from %s, want %s", got, want)
+		}
+		peer.Close()
+		conn.Close()
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+)
+
+// scanState is the -state file: the scope of a scan and which of its
+// targets are finished, so that an interrupted scan can carry on where it
+// stopped. Targets are numbered in the order they are generated; all of
+// them up to Done are finished, plus those in After, which finished ahead
+// of some earlier ones.
+type scanState struct {
+	Scope string   `json:"scope"`
+	Done  uint64   `json:"done"`
+	After []uint64 `json:"after,omitempty"`
+
+	path  string
+	after map[uint64]bool
+}
+
+// loadState reads the state at path, or starts a new one when there is no
+// file yet. Resuming a scan of anything but scope is an error, as the
+// numbering of the targets would not match.
+func loadState(path, scope string) (*scanState, error) {
+	st := &scanState{Scope: scope}
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return nil, err
+	default:
+		if err := json.Unmarshal(data, st); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		if st.Scope != scope {
+			return nil, fmt.Errorf("%s was recorded for %s, not %s; pass the same targets to resume, or another -state file", path, st.Scope, scope)
+		}
+	}
+
+	st.path = path
+	st.after = make(map[uint64]bool, len(st.After))
+	for _, seq := range st.After {
+		st.after[seq] = true
+	}
+	return st, nil
+}
+
+// finished returns the number of targets already finished.
+func (st *scanState) finished() uint64 {
+	return st.Done + uint64(len(st.after))
+}
+
+// finish records that target seq is done.
+func (st *scanState) finish(seq uint64) {
+	if seq <= st.Done {
+		return
+	}
+	st.after[seq] = true
+	for st.after[st.Done+1] {
+		delete(st.after, st.Done+1)
+		st.Done++
+	}
+}
+
+// save writes the state to its file, replacing it atomically.
+func (st *scanState) save() error {
+	st.After = st.After[:0]
+	for seq := range st.after {
+		st.After = append(st.After, seq)
+	}
+	slices.Sort(st.After)
+	return writeStatsFile(st.path, st)
+}
+
+// remaining numbers the targets and passes on only those not finished yet.
+// It reads the state while the caller updates it, so the finished targets
+// are looked up in a copy taken up front.
+func (st *scanState) remaining(targets <-chan Target) <-chan Target {
+	done, after := st.Done, make(map[uint64]bool, len(st.after))
+	for seq := range st.after {
+		after[seq] = true
+	}
+
+	out := make(chan Target, cap(targets))
+	go func() {
+		defer close(out)
+		var seq uint64
+		for t := range targets {
+			seq++
+			if seq <= done || after[seq] {
+				continue
+			}
+			t.seq = seq
+			out <- t
+		}
+	}()
+	return out
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestScanState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scan.state")
+	st, err := loadState(path, "10.0.0.0/29 on port 22")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, seq := range []uint64{2, 1, 5, 3, 7} {
+		st.finish(seq)
+	}
+	if st.Done != 3 || st.finished() != 5 {
+		t.Errorf("after finishing 1,2,3,5,7: Done = %d, finished() = %d; want 3 and 5", st.Done, st.finished())
+	}
+	if err := st.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := loadState(path, "10.0.0.0/24 on port 22"); err == nil || !strings.Contains(err.Error(), "10.0.0.0/29") {
+		t.Errorf("loadState() with another scope error = %v, want one naming the recorded scope", err)
+	}
+
+	st, err = loadState(path, "10.0.0.0/29 on port 22")
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := make(chan Target, 8)
+	for i := range 8 {
+		in <- Target{Host: "10.0.0." + strconv.Itoa(i)}
+	}
+	close(in)
+	var got []uint64
+	for t := range st.remaining(in) {
+		got = append(got, t.seq)
+	}
+	if want := []uint64{4, 6, 8}; !reflect.DeepEqual(got, want) {
+		t.Errorf("remaining() = targets %v, want %v", got, want)
+	}
+}
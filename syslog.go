@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Syslog priority of -syslog messages: facility local0, severity warning
+const syslogPriority = 16*8 + 4
+
+// Structured data ID of -syslog messages. 32473 is the enterprise number
+// RFC 5612 reserves for documentation and private use.
+const syslogSDID = "ssh-scanner@32473"
+
+// parseSyslogTarget checks a -syslog value: udp://host[:port],
+// tcp://host[:port] or unix:///path, with port 514 by default. A bare
+// host[:port] means UDP.
+func parseSyslogTarget(raw string) (network, addr string, err error) {
+	if !strings.Contains(raw, "://") {
+		raw = "udp://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", "", err
+	}
+	switch u.Scheme {
+	case "udp", "tcp":
+		if u.Hostname() == "" {
+			return "", "", fmt.Errorf("%q has no host", raw)
+		}
+		port := u.Port()
+		if port == "" {
+			port = "514"
+		}
+		return u.Scheme, net.JoinHostPort(u.Hostname(), port), nil
+	case "unix":
+		if u.Path == "" {
+			return "", "", fmt.Errorf("%q has no socket path", raw)
+		}
+		return "unixgram", u.Path, nil
+	}
+	return "", "", fmt.Errorf("unsupported scheme %q, expected udp, tcp or unix", u.Scheme)
+}
+
+// syslogWriter sends each found host to a syslog server as an RFC 5424
+// message, for -syslog. Over TCP the messages are framed by octet counting
+// (RFC 6587).
+type syslogWriter struct {
+	mu       sync.Mutex
+	conn     net.Conn
+	stream   bool
+	hostname string
+	pid      int
+}
+
+func dialSyslog(raw string) (*syslogWriter, error) {
+	network, addr, err := parseSyslogTarget(raw)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialTimeout(network, addr, webhookTimeout)
+	if err != nil {
+		return nil, err
+	}
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+	return &syslogWriter{conn: conn, stream: network == "tcp", hostname: hostname, pid: os.Getpid()}, nil
+}
+
+// send writes one message for a found target at now.
+func (w *syslogWriter) send(res Result, now time.Time) error {
+	msg := syslogMessage(res, now, w.hostname, w.pid)
+	if w.stream {
+		msg = strconv.Itoa(len(msg)) + " " + msg
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.conn.SetWriteDeadline(now.Add(webhookTimeout))
+	_, err := w.conn.Write([]byte(msg))
+	return err
+}
+
+func (w *syslogWriter) Close() error {
+	return w.conn.Close()
+}
+
+// syslogMessage formats res as an RFC 5424 message. The fields a SIEM
+// would index go into the structured data; passwords are left out so the
+// findings can be centralized without spreading the credentials with them.
+func syslogMessage(res Result, now time.Time, hostname string, pid int) string {
+	params := []struct{ name, value string }{
+		{"scan_id", res.ScanID},
+		{"tag", res.Tag},
+		{"label", res.Label},
+		{"ip", res.IP},
+		{"port", strconv.Itoa(res.Port)},
+		{"user", res.User},
+		{"hostname", res.Hostname},
+		{"fingerprint", res.Fingerprint},
+		{"auth_methods", strings.Join(res.AuthMethods, ",")},
+		{"access", res.Access},
+		{"route", res.Route},
+	}
+	var sd strings.Builder
+	sd.WriteString("[" + syslogSDID)
+	for _, p := range params {
+		if p.value != "" {
+			fmt.Fprintf(&sd, " %s=\"%s\"", p.name, syslogEscaper.Replace(p.value))
+		}
+	}
+	sd.WriteString("]")
+
+	text := fmt.Sprintf("valid credentials for %s on %s", res.User, net.JoinHostPort(res.IP, strconv.Itoa(res.Port)))
+	if len(res.AuthMethods) > 0 {
+		text = fmt.Sprintf("%s offers auth methods %s", net.JoinHostPort(res.IP, strconv.Itoa(res.Port)), strings.Join(res.AuthMethods, ","))
+	}
+	return fmt.Sprintf("<%d>1 %s %s ssh-scanner %d found %s %s",
+		syslogPriority, now.UTC().Format("2006-01-02T15:04:05.000000Z07:00"), hostname, pid, sd.String(), text)
+}
+
+// Characters RFC 5424 requires escaped in structured data values
+var syslogEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
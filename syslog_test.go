@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSyslogTarget(t *testing.T) {
+	tests := []struct {
+		raw, network, addr string
+	}{
+		{"udp://logs.example.com", "udp", "logs.example.com:514"},
+		{"tcp://10.0.0.5:6514", "tcp", "10.0.0.5:6514"},
+		{"10.0.0.5", "udp", "10.0.0.5:514"},
+		{"unix:///dev/log", "unixgram", "/dev/log"},
+	}
+	for _, tt := range tests {
+		network, addr, err := parseSyslogTarget(tt.raw)
+		if err != nil || network != tt.network || addr != tt.addr {
+			t.Errorf("parseSyslogTarget(%q) = %q, %q, %v; want %q, %q", tt.raw, network, addr, err, tt.network, tt.addr)
+		}
+	}
+
+	for _, bad := range []string{"http://logs.example.com", "udp://", "unix://"} {
+		if _, _, err := parseSyslogTarget(bad); err == nil {
+			t.Errorf("parseSyslogTarget(%q) expected error", bad)
+		}
+	}
+}
+
+func TestSyslogMessage(t *testing.T) {
+	res := Result{IP: "10.0.0.5", Port: 22, User: "root", Password: "hunter2", Success: true,
+		ScanID: "abc", Label: `rack "7"]`}
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	got := syslogMessage(res, now, "scanbox", 42)
+	want := `<132>1 2026-10-16T12:00:00.000000Z scanbox ssh-scanner 42 found ` +
+		`[ssh-scanner@32473 scan_id="abc" label="rack \"7\"\]" ip="10.0.0.5" port="22" user="root"] ` +
+		`valid credentials for root on 10.0.0.5:22`
+	if got != want {
+		t.Errorf("syslogMessage() =\n%s\nwant\n%s", got, want)
+	}
+	if strings.Contains(got, "hunter2") {
+		t.Error("syslogMessage() includes the password")
+	}
+}
+
+func TestSyslogWriterTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	w, err := dialSyslog("tcp://" + ln.Addr().String())
+	if err != nil {
+		t.Fatalf("dialSyslog() error = %v", err)
+	}
+	defer w.Close()
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	res := Result{IP: "10.0.0.5", Port: 22, User: "root", Success: true}
+	if err := w.send(res, time.Now()); err != nil {
+		t.Fatalf("send() error = %v", err)
+	}
+
+	// Octet counting: the length, a space, then exactly that many bytes
+	r := bufio.NewReader(conn)
+	length, err := r.ReadString(' ')
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := make([]byte, len(syslogMessage(res, time.Now(), w.hostname, w.pid)))
+	if strings.TrimSpace(length) != strconv.Itoa(len(msg)) {
+		t.Fatalf("frame length %q, want %d", length, len(msg))
+	}
+	if _, err := io.ReadFull(r, msg); err != nil || !strings.HasPrefix(string(msg), "<132>1 ") {
+		t.Errorf("message %q, %v", msg, err)
+	}
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Target is a single unit of work for the scanner. Zero-valued fields fall
+// back to the global configuration: Port to -P, and User/Password to -u/-p.
+type Target struct {
+	Host     string
+	Port     int
+	User     string
+	Password string
+
+	// Name from the -iL CSV label column, carried into the results
+	Label string
+
+	// Requeue bookkeeping, see Scanner.requeue
+	retries  int
+	slowPass bool
+
+	// Offer legacy algorithms, set once -legacy-fallback found them needed
+	legacy bool
+
+	// Position in the scan's target order, set for -state
+	seq uint64
+}
+
+// rangeTargets validates every CIDR/IP/shortcut spec, including the range
+// shortcuts, up front and then streams
+// one target per address and port. An empty port list uses the global port.
+// The returned total is the number of targets that will be produced, and at
+// most buffer of them are generated ahead of the workers.
+func rangeTargets(specs []string, ports []int, buffer int) (<-chan Target, uint64, error) {
+	type network struct {
+		ip    net.IP
+		ipNet *net.IPNet
+	}
+
+	if len(ports) == 0 {
+		ports = []int{0}
+	}
+
+	var (
+		networks []network
+		total    uint64
+	)
+	for _, spec := range expandShortcuts(specs) {
+		ip, ipNet, err := parseInput(spec)
+		if err != nil {
+			return nil, 0, err
+		}
+		networks = append(networks, network{ip, ipNet})
+		total += countIPs(ipNet) * uint64(len(ports))
+	}
+
+	// Tiny scans don't need buffers sized for the full worker count
+	buffer = int(min(uint64(buffer), 2*max(total, 1)))
+	out := make(chan Target, buffer)
+	go func() {
+		defer close(out)
+		for _, n := range networks {
+			for ip := range generateIPs(n.ip, n.ipNet, buffer) {
+				for _, port := range ports {
+					out <- Target{Host: ip, Port: port}
+				}
+			}
+		}
+	}()
+	return out, total, nil
+}
+
+// parsePorts parses a -ports list such as "22,2222,8000-8100" into the
+// individual ports, in the order given and without duplicates.
+func parsePorts(list string) ([]int, error) {
+	var (
+		ports []int
+		seen  = make(map[int]bool)
+	)
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		lo, hi, isRange := strings.Cut(item, "-")
+
+		first, err := parsePort(lo)
+		if err != nil {
+			return nil, err
+		}
+		last := first
+		if isRange {
+			if last, err = parsePort(hi); err != nil {
+				return nil, err
+			}
+			if last < first {
+				return nil, fmt.Errorf("invalid port range %q", item)
+			}
+		}
+
+		for port := first; port <= last; port++ {
+			if !seen[port] {
+				seen[port] = true
+				ports = append(ports, port)
+			}
+		}
+	}
+	return ports, nil
+}
+
+func parsePort(s string) (int, error) {
+	port, err := strconv.Atoi(s)
+	if err != nil || port < 1 || port > 65535 {
+		return 0, fmt.Errorf("invalid port %q", s)
+	}
+	return port, nil
+}
+
+// sliceTargets streams an already loaded target list.
+func sliceTargets(targets []Target) <-chan Target {
+	out := make(chan Target)
+	go func() {
+		defer close(out)
+		for _, t := range targets {
+			out <- t
+		}
+	}()
+	return out
+}
+
+// readTargetFile loads an -iL target list. See parseTargets for the format.
+func readTargetFile(path string) ([]Target, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return parseTargets(f)
+}
+
+// parseTargets reads one target per line in the form
+//
+//	host[:port] [user [password]]
+//
+// Blank lines and lines starting with '#' are ignored. A user given on the
+// line replaces the global credentials for that target, with a missing
+// password meaning an empty one.
+func parseTargets(r io.Reader) ([]Target, error) {
+	var targets []Target
+
+	sc := bufio.NewScanner(r)
+	for lineNum := 1; sc.Scan(); lineNum++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		t, err := parseTargetLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNum, err)
+		}
+		targets = append(targets, t)
+	}
+	return targets, sc.Err()
+}
+
+func parseTargetLine(line string) (Target, error) {
+	fields := strings.Fields(line)
+	if len(fields) > 3 {
+		return Target{}, fmt.Errorf("expected \"host[:port] [user [password]]\", got %d fields", len(fields))
+	}
+
+	host, port, err := splitHostPort(fields[0])
+	if err != nil {
+		return Target{}, err
+	}
+
+	t := Target{Host: unmapAddr(integerAddr(host)), Port: port}
+	if len(fields) >= 2 {
+		t.User = fields[1]
+	}
+	if len(fields) == 3 {
+		t.Password = fields[2]
+	}
+	return t, nil
+}
+
+// isCSVFile reports whether an -iL file is read as CSV rather than one
+// target per line, which is decided by its .csv extension.
+func isCSVFile(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".csv")
+}
+
+// readTargetCSV loads an -iL CSV file. See parseTargetCSV for the format.
+func readTargetCSV(path, user, password string) ([]Target, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return parseTargetCSV(f, user, password)
+}
+
+// parseTargetCSV reads an asset inventory exported as CSV. The first row
+// names the columns; ip (or host) is required, and port, user, password and
+// label are optional, in any order and case. Other columns are ignored, so
+// an export can be used as is. Lines starting with '#' are skipped.
+//
+// Empty cells fall back to the global settings. Unlike the plain format, a
+// row with a user but no password is tried with the global -p, and one with
+// only a password with the global -u, so user and password are filled in
+// here from user and password.
+func parseTargetCSV(r io.Reader, user, password string) ([]Target, error) {
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	col := map[string]int{"port": -1, "user": -1, "password": -1, "label": -1}
+	hostCol := -1
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "ip", "host":
+			hostCol = i
+		case "port", "user", "password", "label":
+			col[name] = i
+		}
+	}
+	if hostCol < 0 {
+		return nil, errors.New("no ip or host column in the header row")
+	}
+	cell := func(record []string, name string) string {
+		if i := col[name]; i >= 0 {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var targets []Target
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return targets, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+
+		host, port, err := splitHostPort(strings.TrimSpace(record[hostCol]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		if host == "" {
+			return nil, fmt.Errorf("line %d: empty %s", line, header[hostCol])
+		}
+		if p := cell(record, "port"); p != "" {
+			if port, err = parsePort(p); err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+		}
+
+		t := Target{Host: unmapAddr(integerAddr(host)), Port: port, Label: cell(record, "label")}
+		t.User, t.Password = cell(record, "user"), cell(record, "password")
+		if t.User != "" || t.Password != "" {
+			t.User = cmp.Or(t.User, user)
+			t.Password = cmp.Or(t.Password, password)
+		}
+		targets = append(targets, t)
+	}
+}
+
+// splitHostPort accepts "host", "host:port", "[v6]:port" and bare IPv6
+// addresses. A missing port is returned as 0.
+func splitHostPort(s string) (string, int, error) {
+	if ip := net.ParseIP(s); ip != nil {
+		return s, 0, nil
+	}
+	if !strings.Contains(s, ":") {
+		return s, 0, nil
+	}
+
+	host, portStr, err := net.SplitHostPort(s)
+	if err != nil {
+		return "", 0, err
+	}
+	port, err := parsePort(portStr)
+	if err != nil {
+		return "", 0, err
+	}
+	return host, port, nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestParseTargets(t *testing.T) {
+	input := `
+# inventory
+192.168.1.5:2222 admin secret
+192.168.1.6
+192.168.1.7 root
+[fe80::1]:22
+[::ffff:192.168.1.8]:22
+3232235785:22
+host.example.com guest guest
+`
+	got, err := parseTargets(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("parseTargets() error = %v", err)
+	}
+
+	want := []Target{
+		{Host: "192.168.1.5", Port: 2222, User: "admin", Password: "secret"},
+		{Host: "192.168.1.6"},
+		{Host: "192.168.1.7", User: "root"},
+		{Host: "fe80::1", Port: 22},
+		{Host: "192.168.1.8", Port: 22},
+		{Host: "192.168.1.9", Port: 22},
+		{Host: "host.example.com", User: "guest", Password: "guest"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseTargets() = %+v, want %+v", got, want)
+	}
+
+	for _, bad := range []string{"10.0.0.1:0", "10.0.0.1:99999", "10.0.0.1 a b c"} {
+		if _, err := parseTargets(strings.NewReader(bad)); err == nil {
+			t.Errorf("parseTargets(%q) expected error", bad)
+		}
+	}
+}
+
+func TestParsePorts(t *testing.T) {
+	got, err := parsePorts("2222, 22,8000-8003,22")
+	if err != nil {
+		t.Fatalf("parsePorts() error = %v", err)
+	}
+	if want := []int{2222, 22, 8000, 8001, 8002, 8003}; !reflect.DeepEqual(got, want) {
+		t.Errorf("parsePorts() = %v, want %v", got, want)
+	}
+
+	if got, _ := parsePorts("1-65535"); len(got) != 65535 {
+		t.Errorf("parsePorts(1-65535) returned %d ports", len(got))
+	}
+
+	for _, bad := range []string{"", "0", "65536", "22-", "30-20", "ssh"} {
+		if _, err := parsePorts(bad); err == nil {
+			t.Errorf("parsePorts(%q) expected error", bad)
+		}
+	}
+}
+
+func TestRangeTargetsBufferFitsSmallScans(t *testing.T) {
+	targets, total, err := rangeTargets([]string{"10.0.0.0/30"}, nil, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 4 || cap(targets) != 8 {
+		t.Errorf("rangeTargets(/30, buffer 1000): total %d, buffer %d; want 4 and 8", total, cap(targets))
+	}
+	for range targets {
+	}
+}
+
+func TestRangeTargetsBuffer(t *testing.T) {
+	cfg := &Config{Workers: 100}
+	for _, tt := range []struct{ buffer, want int }{{0, 200}, {16, 16}, {800, 800}} {
+		cfg.Buffer = tt.buffer
+		targets, _, err := rangeTargets([]string{"10.0.0.0/24"}, []int{22, 2222}, cfg.bufferSize())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cap(targets) != tt.want {
+			t.Errorf("-buffer %d: channel holds %d targets, want %d", tt.buffer, cap(targets), tt.want)
+		}
+		for range targets {
+		}
+	}
+}
+
+func TestRangeTargetsMemoryIsBounded(t *testing.T) {
+	if testing.Short() {
+		t.Skip("enumerates 16M addresses")
+	}
+
+	var before runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	targets, total, err := rangeTargets([]string{"10.0.0.0/8"}, nil, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		n    uint64
+		peak uint64
+		ms   runtime.MemStats
+	)
+	for range targets {
+		if n++; n%(1<<20) == 0 {
+			runtime.ReadMemStats(&ms)
+			peak = max(peak, ms.HeapInuse)
+		}
+	}
+
+	if n != total || total != 1<<24 {
+		t.Fatalf("enumerated %d of %d targets, want %d", n, total, 1<<24)
+	}
+	// Only the channel buffers and the garbage of the current cycle should be live
+	if grown := int64(peak) - int64(before.HeapInuse); grown > 32<<20 {
+		t.Errorf("heap grew by %d MiB while enumerating a /8", grown>>20)
+	}
+}
+
+func TestParseTargetCSV(t *testing.T) {
+	input := `Label,IP,Port,User,Password,Owner
+# exported from the inventory
+web-1,192.168.1.5,2222,admin,secret,ops
+db-1,"192.168.1.6",,,,dba
+router,192.168.1.7,,root,,net
+switch,192.168.1.8:2200,,,guest,net
+`
+	got, err := parseTargetCSV(strings.NewReader(input), "test", "123456")
+	if err != nil {
+		t.Fatalf("parseTargetCSV() error = %v", err)
+	}
+
+	want := []Target{
+		{Host: "192.168.1.5", Port: 2222, User: "admin", Password: "secret", Label: "web-1"},
+		{Host: "192.168.1.6", Label: "db-1"},
+		{Host: "192.168.1.7", User: "root", Password: "123456", Label: "router"},
+		{Host: "192.168.1.8", Port: 2200, User: "test", Password: "guest", Label: "switch"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseTargetCSV() = %+v, want %+v", got, want)
+	}
+
+	for _, bad := range []string{
+		"label,user\nweb-1,admin\n",
+		"ip,port\n10.0.0.1,0\n",
+		"ip,port\n10.0.0.1\n",
+		"ip\n\"\"\n",
+	} {
+		if _, err := parseTargetCSV(strings.NewReader(bad), "test", ""); err == nil {
+			t.Errorf("parseTargetCSV(%q) expected error", bad)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Rows taken by the header and footer around the results list
+const tuiChromeRows = 6
+
+type (
+	tuiResultMsg Result
+	tuiDoneMsg   struct{}
+	tuiTickMsg   time.Time
+)
+
+// tuiModel is the bubbletea model behind -tui. It only renders state; the
+// scan itself runs in Scanner.Run and reports through messages.
+type tuiModel struct {
+	cfg     *Config
+	scanner *Scanner
+	cancel  context.CancelFunc
+	title   string
+	total   uint64
+	start   time.Time
+
+	found  []Result
+	offset int  // first visible row of the filtered list
+	follow bool // keep the newest result in view
+
+	filter    string
+	filtering bool
+
+	width, height int
+	done          bool
+	elapsed       time.Duration
+}
+
+// runTUI scans targets while showing an interactive full-screen view, then
+// prints the found hosts to stdout once the view is closed.
+func runTUI(targets <-chan Target, cfg *Config, total uint64, title string) error {
+	f, err := createResultFiles(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m := &tuiModel{
+		cfg:     cfg,
+		scanner: NewScanner(cfg),
+		cancel:  cancel,
+		title:   title,
+		total:   total,
+		start:   time.Now(),
+		follow:  true,
+		height:  24,
+	}
+	p := tea.NewProgram(m, tea.WithAltScreen())
+
+	var (
+		closeErr error
+		drained  = make(chan struct{})
+	)
+	results := make(chan Result, cfg.Workers)
+	go m.scanner.Run(ctx, targets, results)
+	var reported <-chan Result = results
+	if cfg.ResolveNames {
+		reported = resolveNames(results, net.DefaultResolver.LookupAddr, cfg)
+	}
+	go func() {
+		defer close(drained)
+		for res := range reported {
+			if f != nil {
+				f.addResult(res)
+			}
+			if !res.Success {
+				continue
+			}
+			if f != nil {
+				f.add(resultLine(res, cfg))
+			}
+			p.Send(tuiResultMsg(res))
+		}
+		if f != nil {
+			closeErr = f.Close()
+		}
+		p.Send(tuiDoneMsg{})
+	}()
+
+	_, err = p.Run()
+
+	// Quitting early cancels dispatch; wait for the attempts in flight so
+	// the output file, and its -sign signature, are complete
+	cancel()
+	<-drained
+	if err != nil {
+		return err
+	}
+	if closeErr != nil {
+		return fmt.Errorf("failed to write output file: %v", closeErr)
+	}
+
+	// The alternate screen is gone, so leave the findings in the scrollback
+	for _, res := range m.found {
+		fmt.Printf("%s[+] %s%s\n", ColorGreen, resultLine(res, cfg), ColorReset)
+	}
+	stats := m.scanner.Stats()
+	fmt.Printf("Processed %d/%d, found %s%d%s\n", stats.Processed, total, ColorGreen, stats.Found, ColorReset)
+	return nil
+}
+
+func tuiTick() tea.Cmd {
+	return tea.Tick(500*time.Millisecond, func(t time.Time) tea.Msg { return tuiTickMsg(t) })
+}
+
+func (m *tuiModel) Init() tea.Cmd {
+	return tuiTick()
+}
+
+func (m *tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+	case tuiTickMsg:
+		if !m.done {
+			return m, tuiTick()
+		}
+	case tuiResultMsg:
+		m.found = append(m.found, Result(msg))
+	case tuiDoneMsg:
+		m.done = true
+		m.elapsed = time.Since(m.start)
+	case tea.KeyMsg:
+		if m.filtering {
+			m.updateFilter(msg)
+		} else if quit := m.updateKeys(msg); quit {
+			m.cancel()
+			return m, tea.Quit
+		}
+	}
+	m.clampScroll()
+	return m, nil
+}
+
+func (m *tuiModel) updateFilter(msg tea.KeyMsg) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		m.filtering = false
+	case tea.KeyEsc:
+		m.filtering = false
+		m.filter = ""
+	case tea.KeyBackspace:
+		_, size := utf8.DecodeLastRuneInString(m.filter)
+		m.filter = m.filter[:len(m.filter)-size]
+	case tea.KeyRunes, tea.KeySpace:
+		m.filter += string(msg.Runes)
+	}
+	m.follow = true
+}
+
+// updateKeys handles navigation keys and reports whether to quit.
+func (m *tuiModel) updateKeys(msg tea.KeyMsg) bool {
+	page := max(m.listRows()-1, 1)
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return true
+	case "p", " ":
+		if m.scanner.Paused() {
+			m.scanner.Resume()
+		} else {
+			m.scanner.Pause()
+		}
+	case "/":
+		m.filtering = true
+	case "esc":
+		m.filter = ""
+	case "up", "k":
+		m.offset--
+		m.follow = false
+	case "down", "j":
+		m.offset++
+	case "pgup":
+		m.offset -= page
+		m.follow = false
+	case "pgdown":
+		m.offset += page
+	case "home", "g":
+		m.offset = 0
+		m.follow = false
+	case "end", "G":
+		m.follow = true
+	}
+	return false
+}
+
+func (m *tuiModel) listRows() int {
+	return max(m.height-tuiChromeRows, 1)
+}
+
+func (m *tuiModel) visible() []Result {
+	if m.filter == "" {
+		return m.found
+	}
+	var out []Result
+	for _, res := range m.found {
+		if strings.Contains(m.row(res), m.filter) {
+			out = append(out, res)
+		}
+	}
+	return out
+}
+
+func (m *tuiModel) clampScroll() {
+	last := max(len(m.visible())-m.listRows(), 0)
+	if m.follow || m.offset >= last {
+		m.offset = last
+		m.follow = true
+	}
+	m.offset = max(m.offset, 0)
+}
+
+func (m *tuiModel) row(res Result) string {
+	return strings.TrimSpace(fmt.Sprintf("%-22s %-12s %-8v %s %s",
+		foundLabel(res, m.cfg), res.User, res.Duration.Round(time.Millisecond), res.Fingerprint, res.Hostname))
+}
+
+func (m *tuiModel) View() string {
+	var b strings.Builder
+
+	stats := m.scanner.Stats()
+	elapsed := m.elapsed
+	if !m.done {
+		elapsed = time.Since(m.start)
+	}
+	rate := float64(stats.Processed) / max(elapsed.Seconds(), 0.001)
+	percent := 0.0
+	if m.total > 0 {
+		percent = float64(stats.Processed) / float64(m.total) * 100
+	}
+
+	state := ColorCyan + "RUNNING"
+	switch {
+	case m.done:
+		state = ColorGreen + "DONE"
+	case m.scanner.Paused():
+		state = ColorYellow + "PAUSED"
+	}
+	fmt.Fprintf(&b, " ssh-scanner | %s as %s | %s%s\n", m.title, m.cfg.User, state, ColorReset)
+	fmt.Fprintf(&b, " Progress %d/%d (%.1f%%) | Found %s%d%s | Failed %d | Rate %.1f/s | Workers %d/%d | %v\n",
+		stats.Processed, m.total, percent, ColorGreen, stats.Found, ColorReset, stats.Failed,
+		rate, stats.Active, m.cfg.Workers, elapsed.Round(time.Second))
+	b.WriteString(m.rule())
+
+	rows := m.visible()
+	end := min(m.offset+m.listRows(), len(rows))
+	for _, res := range rows[m.offset:end] {
+		fmt.Fprintf(&b, " %s[+]%s %s\n", ColorGreen, ColorReset, m.row(res))
+	}
+	for range m.listRows() - (end - m.offset) {
+		b.WriteString("\n")
+	}
+
+	b.WriteString(m.rule())
+	switch {
+	case m.filtering:
+		fmt.Fprintf(&b, " filter: %s_  (enter apply, esc clear)", m.filter)
+	case m.filter != "":
+		fmt.Fprintf(&b, " filter: %q (%d/%d)  esc clear | p pause | / filter | ↑↓ scroll | q quit", m.filter, len(rows), len(m.found))
+	default:
+		b.WriteString(" p pause/resume | / filter | ↑↓ pgup pgdn scroll | q quit")
+	}
+	return b.String()
+}
+
+func (m *tuiModel) rule() string {
+	return " " + strings.Repeat("─", max(m.width-2, 10)) + "\n"
+}
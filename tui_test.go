@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestTUIModelFilterAndPause(t *testing.T) {
+	cfg := &Config{Port: 22, Workers: 4}
+	m := &tuiModel{cfg: cfg, scanner: NewScanner(cfg), follow: true, height: 24, cancel: func() {}}
+
+	for _, ip := range []string{"10.0.0.1", "10.0.1.1", "10.0.0.2"} {
+		m.Update(tuiResultMsg{IP: ip, Port: 22, Success: true})
+	}
+	if got := len(m.visible()); got != 3 {
+		t.Fatalf("visible() = %d rows, want 3", got)
+	}
+
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("10.0.0.")})
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if got := len(m.visible()); got != 2 || m.filtering {
+		t.Errorf("after filtering visible() = %d rows (filtering %v), want 2", got, m.filtering)
+	}
+
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("p")})
+	if !m.scanner.Paused() {
+		t.Errorf("p did not pause the scanner")
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("p")})
+	if m.scanner.Paused() {
+		t.Errorf("second p did not resume the scanner")
+	}
+}
+
+func TestTUIModelFilterBackspace(t *testing.T) {
+	m := &tuiModel{cfg: &Config{}, filtering: true}
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("héé")})
+	m.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	if m.filter != "hé" {
+		t.Errorf("after backspace filter = %q, want %q", m.filter, "hé")
+	}
+	for range 3 {
+		m.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	}
+	if m.filter != "" {
+		t.Errorf("backspace past the start left filter = %q", m.filter)
+	}
+}
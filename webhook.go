@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Each -webhook delivery gets webhookTimeout, and a failed one is tried up to
+// webhookAttempts times in all, webhookBackoff apart.
+const (
+	webhookTimeout  = 10 * time.Second
+	webhookAttempts = 3
+	webhookBackoff  = 2 * time.Second
+)
+
+// webhookPayload is the document -webhook posts when a scan ends. Text and
+// Content carry a one-line summary, which Slack and Discord incoming webhooks
+// respectively display; other receivers can use the structured fields.
+type webhookPayload struct {
+	Text    string `json:"text"`
+	Content string `json:"content"`
+
+	statsSnapshot
+	Interrupted bool     `json:"interrupted"`
+	TimedOut    bool     `json:"timed_out,omitempty"`
+	Results     []Result `json:"results,omitempty"`
+}
+
+func newWebhookPayload(snapshot statsSnapshot, interrupted, timedOut bool, results []Result) webhookPayload {
+	verb := "finished"
+	switch {
+	case timedOut:
+		verb = "stopped at -max-time"
+	case interrupted:
+		verb = "was interrupted"
+	}
+	text := fmt.Sprintf("SSH scan %s %s: %d found, %d of %d targets processed in %s",
+		snapshot.ScanID, verb, snapshot.Found, snapshot.Processed, snapshot.Total,
+		time.Duration(snapshot.Elapsed*float64(time.Second)).Round(time.Second))
+	if snapshot.Tag != "" {
+		text += fmt.Sprintf(" (tag %q)", snapshot.Tag)
+	}
+	return webhookPayload{
+		Text:          text,
+		Content:       text,
+		statsSnapshot: snapshot,
+		Interrupted:   interrupted,
+		TimedOut:      timedOut,
+		Results:       results,
+	}
+}
+
+// parseWebhookURL checks a -webhook value up front, so a typo shows before
+// the scan rather than after it.
+func parseWebhookURL(raw string) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("unsupported scheme %q, expected http or https", u.Scheme)
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("%q has no host", raw)
+	}
+	return u.String(), nil
+}
+
+// postWebhook delivers v as JSON to target, retrying failed attempts, both
+// network errors and non-2xx responses. It returns the last error once all
+// attempts failed.
+func postWebhook(target string, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: webhookTimeout}
+	for attempt := 1; ; attempt++ {
+		err = postOnce(client, target, body)
+		if err == nil || attempt == webhookAttempts {
+			return err
+		}
+		time.Sleep(webhookBackoff)
+	}
+}
+
+func postOnce(client *http.Client, target string, body []byte) error {
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPostWebhook(t *testing.T) {
+	var calls atomic.Int32
+	var got webhookPayload
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The first delivery fails and must be retried
+		if calls.Add(1) == 1 {
+			http.Error(w, "try later", http.StatusServiceUnavailable)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer srv.Close()
+
+	snapshot := statsSnapshot{ScanID: "abc", Stats: Stats{Processed: 256, Found: 2}, Total: 256, Elapsed: 90}
+	results := []Result{{IP: "10.0.0.1", Port: 22, User: "root", Success: true}}
+	if err := postWebhook(srv.URL, newWebhookPayload(snapshot, true, false, results)); err != nil {
+		t.Fatalf("postWebhook() = %v", err)
+	}
+
+	if n := calls.Load(); n != 2 {
+		t.Errorf("webhook called %d times, want 2", n)
+	}
+	if !got.Interrupted || got.Found != 2 || len(got.Results) != 1 || got.Results[0].IP != "10.0.0.1" {
+		t.Errorf("payload = %+v", got)
+	}
+	if want := "SSH scan abc was interrupted: 2 found, 256 of 256 targets processed in 1m30s"; got.Text != want || got.Content != want {
+		t.Errorf("summary = %q / %q, want %q", got.Text, got.Content, want)
+	}
+}
+
+func TestParseWebhookURL(t *testing.T) {
+	for _, raw := range []string{"https://hooks.example.com/T0/B0", "http://localhost:8080/hook"} {
+		if _, err := parseWebhookURL(raw); err != nil {
+			t.Errorf("parseWebhookURL(%q) = %v", raw, err)
+		}
+	}
+	for _, raw := range []string{"hooks.example.com/x", "ftp://example.com/", "https:///path"} {
+		if _, err := parseWebhookURL(raw); err == nil {
+			t.Errorf("parseWebhookURL(%q) accepted an invalid URL", raw)
+		}
+	}
+}
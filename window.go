@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// timeWindow is a daily local-time interval during which dialing is allowed.
+// A window whose end is before its start wraps past midnight.
+type timeWindow struct {
+	start, end time.Duration // offsets from local midnight
+	spec       string
+}
+
+// parseWindow parses "HH:MM-HH:MM", e.g. "22:00-04:00".
+func parseWindow(s string) (*timeWindow, error) {
+	from, to, ok := strings.Cut(s, "-")
+	if !ok {
+		return nil, fmt.Errorf("expected HH:MM-HH:MM, got %q", s)
+	}
+	start, err := parseClock(from)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseClock(to)
+	if err != nil {
+		return nil, err
+	}
+	if start == end {
+		return nil, fmt.Errorf("window %q is empty", s)
+	}
+	return &timeWindow{start: start, end: end, spec: s}, nil
+}
+
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func (w *timeWindow) String() string {
+	return w.spec
+}
+
+// contains reports whether t falls inside the window.
+func (w *timeWindow) contains(t time.Time) bool {
+	offset := sinceMidnight(t)
+	if w.start < w.end {
+		return offset >= w.start && offset < w.end
+	}
+	return offset >= w.start || offset < w.end
+}
+
+// nextOpen returns the next time at or after t when the window opens.
+func (w *timeWindow) nextOpen(t time.Time) time.Time {
+	y, m, d := t.Date()
+	open := time.Date(y, m, d, 0, 0, 0, 0, t.Location()).Add(w.start)
+	if open.Before(t) {
+		open = time.Date(y, m, d+1, 0, 0, 0, 0, t.Location()).Add(w.start)
+	}
+	return open
+}
+
+// wait blocks until the window is open or ctx is done and returns how long
+// it waited.
+func (w *timeWindow) wait(ctx context.Context) time.Duration {
+	start := time.Now()
+	for now := start; !w.contains(now); now = time.Now() {
+		timer := time.NewTimer(time.Until(w.nextOpen(now)))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return time.Since(start)
+		}
+	}
+	return time.Since(start)
+}
+
+func sinceMidnight(t time.Time) time.Duration {
+	h, m, s := t.Clock()
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(s)*time.Second
+}
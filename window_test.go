@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeWindow(t *testing.T) {
+	at := func(clock string) time.Time {
+		c, _ := time.Parse("15:04", clock)
+		return time.Date(2024, 5, 1, c.Hour(), c.Minute(), 0, 0, time.Local)
+	}
+
+	overnight, err := parseWindow("22:00-04:00")
+	if err != nil {
+		t.Fatalf("parseWindow() error = %v", err)
+	}
+	for clock, want := range map[string]bool{"21:59": false, "22:00": true, "23:30": true, "03:59": true, "04:00": false, "12:00": false} {
+		if got := overnight.contains(at(clock)); got != want {
+			t.Errorf("22:00-04:00 contains %s = %v, want %v", clock, got, want)
+		}
+	}
+	if got, want := overnight.nextOpen(at("12:00")), at("22:00"); !got.Equal(want) {
+		t.Errorf("nextOpen(12:00) = %v, want %v", got, want)
+	}
+
+	daytime, _ := parseWindow("09:00-17:00")
+	if got, want := daytime.nextOpen(at("18:00")), at("09:00").AddDate(0, 0, 1); !got.Equal(want) {
+		t.Errorf("nextOpen(18:00) = %v, want %v", got, want)
+	}
+
+	for _, bad := range []string{"22:00", "25:00-01:00", "10:00-10:00"} {
+		if _, err := parseWindow(bad); err == nil {
+			t.Errorf("parseWindow(%q) expected error", bad)
+		}
+	}
+}